@@ -5,15 +5,31 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
 		CREATE TABLE public.jobs
     (
         id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        namespace character varying(63) COLLATE pg_catalog."default" NOT NULL DEFAULT 'default',
         name character varying(255) COLLATE pg_catalog."default" NOT NULL,
         crontabstring character varying(50) COLLATE pg_catalog."default" NOT NULL,
+        mode character varying(16) COLLATE pg_catalog."default" NOT NULL DEFAULT 'command',
         command character varying(512) COLLATE pg_catalog."default" NOT NULL,
+        script text COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        interpreter character varying(64) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
         timeout bigint NOT NULL,
         nextexecutiontime timestamp with time zone,
         running boolean NOT NULL DEFAULT false,
         arguments character varying[] COLLATE pg_catalog."default",
-        CONSTRAINT jobs_pkey PRIMARY KEY (id),
-        CONSTRAINT unique_name UNIQUE (name)
+        runasuser character varying(64) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        runasgroup character varying(64) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        addressspacelimit bigint NOT NULL DEFAULT 0,
+        cpulimit bigint NOT NULL DEFAULT 0,
+        openfileslimit bigint NOT NULL DEFAULT 0,
+        processeslimit bigint NOT NULL DEFAULT 0,
+        deletedat timestamp with time zone,
+        revision integer NOT NULL DEFAULT 1,
+        labels jsonb NOT NULL DEFAULT '{}',
+        paused boolean NOT NULL DEFAULT false,
+        maxsuccessinterval bigint NOT NULL DEFAULT 0,
+        slaalertedat timestamp with time zone,
+        claimedat timestamp with time zone,
+        CONSTRAINT jobs_pkey PRIMARY KEY (id)
     );
     ALTER TABLE IF EXISTS public.jobs
         OWNER to "$POSTGRES_USER";
@@ -21,5 +37,216 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
 
     CREATE INDEX jobs_nextexecutiontime_idx
         ON public.jobs USING btree
-        (nextexecutiontime ASC NULLS LAST)
+        (nextexecutiontime ASC NULLS LAST);
+
+    -- Names are unique within a namespace, names of deleted jobs can be reused
+    CREATE UNIQUE INDEX jobs_unique_name_idx
+        ON public.jobs USING btree
+        (namespace ASC, name ASC)
+        WHERE deletedat IS NULL;
+
+    -- Supports the containment (@>) and key existence (?) operators label selectors are translated to
+    CREATE INDEX jobs_labels_idx
+        ON public.jobs USING gin
+        (labels);
+
+    CREATE INDEX jobs_deletedat_idx
+        ON public.jobs USING btree
+        (deletedat ASC)
+        WHERE deletedat IS NOT NULL;
+
+    CREATE TABLE public.jobrevisions
+    (
+        jobid bigint NOT NULL,
+        revision integer NOT NULL,
+        createdat timestamp with time zone NOT NULL,
+        actor character varying(255) COLLATE pg_catalog."default" NOT NULL,
+        definition jsonb NOT NULL,
+        CONSTRAINT jobrevisions_pkey PRIMARY KEY (jobid, revision),
+        CONSTRAINT jobrevisions_jobid_fkey FOREIGN KEY (jobid) REFERENCES public.jobs (id) ON DELETE CASCADE
+    );
+    ALTER TABLE IF EXISTS public.jobrevisions
+        OWNER to "$POSTGRES_USER";
+    -- Revisions are immutable, they are only removed along with their job
+    GRANT SELECT, INSERT ON public.jobrevisions TO "go-work";
+
+    CREATE TABLE public.runs
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        jobid bigint NOT NULL,
+        jobrevision integer,
+        schedulerid bigint,
+        status character varying(16) COLLATE pg_catalog."default" NOT NULL,
+        starttime timestamp with time zone NOT NULL,
+        endtime timestamp with time zone,
+        exitcode integer,
+        error text COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        terminatedby character varying(32) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        usercpuseconds double precision,
+        systemcpuseconds double precision,
+        maxrssbytes bigint,
+        outputtail text COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        cancelrequestedat timestamp with time zone,
+        CONSTRAINT runs_pkey PRIMARY KEY (id),
+        CONSTRAINT runs_jobid_fkey FOREIGN KEY (jobid) REFERENCES public.jobs (id) ON DELETE CASCADE
+    );
+    ALTER TABLE IF EXISTS public.runs
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, UPDATE, INSERT, DELETE ON public.runs TO "go-work";
+
+    CREATE INDEX runs_jobid_starttime_idx
+        ON public.runs USING btree
+        (jobid ASC, starttime DESC);
+
+    -- Schedulers register themselves when they start and record heartbeats while they run. Runs keep the
+    -- ids of the schedulers which started them
+    CREATE TABLE public.schedulers
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        hostname character varying(255) COLLATE pg_catalog."default" NOT NULL,
+        pinginterval double precision NOT NULL,
+        version character varying(64) COLLATE pg_catalog."default" NOT NULL,
+        starttime timestamp with time zone NOT NULL,
+        lastheartbeat timestamp with time zone NOT NULL,
+        stoptime timestamp with time zone,
+        CONSTRAINT schedulers_pkey PRIMARY KEY (id)
+    );
+    ALTER TABLE IF EXISTS public.schedulers
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, UPDATE, INSERT ON public.schedulers TO "go-work";
+
+    -- Rules without a job apply to all jobs of their namespace
+    CREATE TABLE public.notificationrules
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        namespace character varying(63) COLLATE pg_catalog."default" NOT NULL,
+        jobid bigint,
+        events character varying(32)[] COLLATE pg_catalog."default" NOT NULL,
+        consecutivefailures integer NOT NULL DEFAULT 0,
+        webhookurl character varying(2048) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        webhookformat character varying(16) COLLATE pg_catalog."default" NOT NULL DEFAULT 'json',
+        webhooksecret character varying(255) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        emailto character varying(254)[] COLLATE pg_catalog."default" NOT NULL DEFAULT '{}',
+        createdat timestamp with time zone NOT NULL,
+        CONSTRAINT notificationrules_pkey PRIMARY KEY (id),
+        CONSTRAINT notificationrules_jobid_fkey FOREIGN KEY (jobid) REFERENCES public.jobs (id) ON DELETE CASCADE
+    );
+    ALTER TABLE IF EXISTS public.notificationrules
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, INSERT, DELETE ON public.notificationrules TO "go-work";
+
+    CREATE INDEX notificationrules_namespace_jobid_idx
+        ON public.notificationrules USING btree
+        (namespace ASC, jobid ASC);
+
+    CREATE TABLE public.notificationdeliveries
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        ruleid bigint NOT NULL,
+        jobid bigint,
+        runid bigint,
+        events character varying(32)[] COLLATE pg_catalog."default" NOT NULL,
+        status character varying(16) COLLATE pg_catalog."default" NOT NULL,
+        attempts integer NOT NULL DEFAULT 0,
+        responsecode integer,
+        error text COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        createdat timestamp with time zone NOT NULL,
+        finishedat timestamp with time zone,
+        CONSTRAINT notificationdeliveries_pkey PRIMARY KEY (id),
+        CONSTRAINT notificationdeliveries_ruleid_fkey FOREIGN KEY (ruleid) REFERENCES public.notificationrules (id) ON DELETE CASCADE
+    );
+    ALTER TABLE IF EXISTS public.notificationdeliveries
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, UPDATE, INSERT ON public.notificationdeliveries TO "go-work";
+
+    CREATE INDEX notificationdeliveries_ruleid_idx
+        ON public.notificationdeliveries USING btree
+        (ruleid ASC, id DESC);
+
+    -- Namespaces without a quota are unlimited, as are the zero fields of quotas
+    CREATE TABLE public.namespacequotas
+    (
+        namespace character varying(63) COLLATE pg_catalog."default" NOT NULL,
+        maxjobs bigint NOT NULL DEFAULT 0,
+        maxconcurrentruns bigint NOT NULL DEFAULT 0,
+        maxtimeoutperhour bigint NOT NULL DEFAULT 0,
+        CONSTRAINT namespacequotas_pkey PRIMARY KEY (namespace)
+    );
+    ALTER TABLE IF EXISTS public.namespacequotas
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, UPDATE, INSERT, DELETE ON public.namespacequotas TO "go-work";
+
+    -- Timeouts charged against the hourly quotas of namespaces when their jobs are claimed, older ones are
+    -- deleted once they leave the one-hour window
+    CREATE TABLE public.quotacharges
+    (
+        namespace character varying(63) COLLATE pg_catalog."default" NOT NULL,
+        chargedat timestamp with time zone NOT NULL,
+        timeout bigint NOT NULL
+    );
+    ALTER TABLE IF EXISTS public.quotacharges
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, INSERT, DELETE ON public.quotacharges TO "go-work";
+
+    CREATE INDEX quotacharges_namespace_chargedat_idx
+        ON public.quotacharges USING btree
+        (namespace ASC, chargedat ASC);
+
+    CREATE INDEX jobs_namespace_running_idx
+        ON public.jobs USING btree
+        (namespace ASC)
+        WHERE running;
+
+    CREATE TABLE public.apikeys
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        name character varying(255) COLLATE pg_catalog."default" NOT NULL,
+        role character varying(16) COLLATE pg_catalog."default" NOT NULL DEFAULT 'viewer',
+        namespaces character varying(63)[] COLLATE pg_catalog."default" NOT NULL DEFAULT '{}',
+        keyhash bytea NOT NULL,
+        createdat timestamp with time zone NOT NULL,
+        lastusedat timestamp with time zone,
+        CONSTRAINT apikeys_pkey PRIMARY KEY (id),
+        CONSTRAINT apikeys_unique_name UNIQUE (name),
+        CONSTRAINT apikeys_unique_keyhash UNIQUE (keyhash)
+    );
+    ALTER TABLE IF EXISTS public.apikeys
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT, UPDATE, INSERT, DELETE ON public.apikeys TO "go-work";
+
+    CREATE TABLE public.audit
+    (
+        id bigint NOT NULL GENERATED ALWAYS AS IDENTITY ( INCREMENT 1 START 1 MINVALUE 1 MAXVALUE 9223372036854775807 CACHE 1 ),
+        recordedat timestamp with time zone NOT NULL,
+        actor character varying(255) COLLATE pg_catalog."default" NOT NULL,
+        requestid character varying(128) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+        action character varying(16) COLLATE pg_catalog."default" NOT NULL,
+        jobid bigint NOT NULL,
+        jobname character varying(255) COLLATE pg_catalog."default" NOT NULL,
+        before jsonb,
+        after jsonb,
+        CONSTRAINT audit_pkey PRIMARY KEY (id)
+    );
+    ALTER TABLE IF EXISTS public.audit
+        OWNER to "$POSTGRES_USER";
+    -- The audit log is append-only for the app
+    GRANT SELECT, INSERT ON public.audit TO "go-work";
+
+    CREATE INDEX audit_jobid_idx
+        ON public.audit USING btree
+        (jobid ASC, id DESC);
+
+    CREATE INDEX audit_actor_idx
+        ON public.audit USING btree
+        (actor ASC, id DESC);
+
+    -- Checked by the app's readiness endpoint, increase it along with model.SchemaVersion on schema changes
+    CREATE TABLE public.schemaversion
+    (
+        version integer NOT NULL
+    );
+    ALTER TABLE IF EXISTS public.schemaversion
+        OWNER to "$POSTGRES_USER";
+    GRANT SELECT ON public.schemaversion TO "go-work";
+    INSERT INTO public.schemaversion (version) VALUES (9);
 EOSQL
\ No newline at end of file
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jessevdk/go-flags"
+	"go-work/internal/auth"
+	"go-work/internal/model"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type APIKeyCommand struct {
+	Create struct {
+		Name       string   `long:"name" description:"Name of the API key" required:"true"`
+		Role       string   `long:"role" description:"Role of the API key" choice:"viewer" choice:"operator" choice:"admin" default:"viewer"`
+		Namespaces []string `long:"namespace" description:"Namespace the API key is restricted to. Without it, the key can access all namespaces"`
+	} `command:"create" description:"Create an API key and print it"`
+	List   struct{} `command:"list" description:"List API keys"`
+	Delete struct {
+		Name string `long:"name" description:"Name of the API key" required:"true"`
+	} `command:"delete" description:"Delete an API key"`
+}
+
+func runAPIKeyCommand(ctx context.Context, command *flags.Command, opts *APIKeyCommand, dataSourceName string) error {
+	storage, err := model.NewSQLAPIKeyStorage(ctx, "postgres", dataSourceName)
+	if err != nil {
+		return fmt.Errorf("failed opening storage: %w", err)
+	}
+	defer storage.Close()
+
+	switch command.Name {
+	case "create":
+		for _, namespace := range opts.Create.Namespaces {
+			if err = model.ValidateNamespace(namespace); err != nil {
+				return err
+			}
+		}
+		key, keyHash, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		if _, err = storage.CreateAPIKey(ctx, opts.Create.Name, auth.Role(opts.Create.Role), opts.Create.Namespaces, keyHash); err != nil {
+			return err
+		}
+		fmt.Println(key)
+	case "list":
+		keys, err := storage.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tROLE\tNAMESPACES\tCREATED\tLAST USED")
+		for _, key := range keys {
+			lastUsed := "never"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			namespaces := "all"
+			if len(key.Namespaces) != 0 {
+				namespaces = strings.Join(key.Namespaces, ",")
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\n",
+				key.Name,
+				key.Role,
+				namespaces,
+				key.CreatedAt.Format(time.RFC3339),
+				lastUsed,
+			)
+		}
+		return w.Flush()
+	case "delete":
+		return storage.DeleteAPIKey(ctx, opts.Delete.Name)
+	}
+	return nil
+}
@@ -5,10 +5,21 @@ import (
 	"fmt"
 	"github.com/jessevdk/go-flags"
 	_ "github.com/lib/pq"
-	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	"go-work/internal/execution"
 	"go-work/internal/http"
+	"go-work/internal/logging"
+	"go-work/internal/metrics"
 	"go-work/internal/model"
+	"go-work/internal/notification"
+	"go-work/internal/reaper"
+	"go-work/internal/retention"
 	"go-work/internal/scheduler"
+	"go-work/internal/shell"
+	"go-work/internal/sla"
+	"go-work/internal/tracing"
+	nhttp "net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"sync"
@@ -17,22 +28,87 @@ import (
 )
 
 type Options struct {
-	ServerPort uint   `long:"server-port" description:"Port for server to listen on" default:"8080"`
-	DbHost     string `long:"db-host" description:"Database host" required:"true"`
-	DbPort     uint   `long:"db-port" description:"Database port" default:"5432"`
-	Intervals  []uint `long:"interval" description:"Query intervals for schedulers" required:"true"`
+	ServerPort    uint          `long:"server-port" description:"Port for server to listen on" default:"8080"`
+	MetricsPort   uint          `long:"metrics-port" description:"Port to serve Prometheus metrics on at /metrics, 0 disables the endpoint"`
+	DbHost        string        `long:"db-host" description:"Database host" required:"true"`
+	DbPort        uint          `long:"db-port" description:"Database port" default:"5432"`
+	Intervals     []uint        `long:"interval" description:"Query intervals for schedulers, required when serving"`
+	Interpreters  []string      `long:"interpreter" description:"Interpreter for shell-mode jobs in the form name=command [args...]. Defaults to sh, bash and python3"`
+	AllowedUsers  []string      `long:"allowed-user" description:"User which jobs are allowed to run as"`
+	AllowedGroups []string      `long:"allowed-group" description:"Group which jobs are allowed to run as"`
+	CommandPolicy string        `long:"command-policy" description:"JSON file with the policy restricting commands jobs are allowed to run. It is reloaded on SIGHUP"`
+	JobEnv        []string      `long:"job-env" description:"Environment variable of the app which is passed on to jobs. Jobs only get PATH, variables describing their user and the trace context otherwise"`
+	JobRetention  time.Duration `long:"deleted-job-retention" description:"How long deleted jobs can be restored before they are purged along with their runs, 0 keeps them forever" default:"720h"`
+	SLACheck      time.Duration `long:"sla-check-interval" description:"How often jobs are checked for exceeding their maximum success interval, 0 disables the check" default:"1m"`
+	Reclaim       time.Duration `long:"reclaim-interval" description:"How often jobs which stayed marked running after their timeout are reclaimed, 0 disables reclaiming" default:"1m"`
+	ReclaimGrace  time.Duration `long:"reclaim-grace" description:"Time after a job's timeout before it is reclaimed if no live scheduler is running it" default:"5m"`
+	DefaultLimits struct {
+		AddressSpace uint64 `long:"default-address-space-limit" description:"Default address space limit of job processes in bytes, 0 means unlimited"`
+		CPUSeconds   uint64 `long:"default-cpu-limit" description:"Default CPU time limit of job processes in seconds, 0 means unlimited"`
+		OpenFiles    uint64 `long:"default-open-files-limit" description:"Default limit of open files of job processes, 0 means unlimited"`
+		Processes    uint64 `long:"default-processes-limit" description:"Default limit of processes of the user running a job, 0 means unlimited"`
+	} `group:"Resource limits"`
+	Tokens struct {
+		JWKS           string   `long:"jwks" description:"JWKS file or http(s) URL with the keys bearer tokens are signed with. Enables bearer token authentication. It is reloaded on SIGHUP"`
+		Issuer         string   `long:"jwt-issuer" description:"Required issuer of bearer tokens"`
+		Audience       string   `long:"jwt-audience" description:"Required audience of bearer tokens"`
+		RoleClaim      string   `long:"jwt-role-claim" description:"Bearer token claim holding the client's roles, nested claims are separated by dots" default:"role"`
+		Roles          []string `long:"jwt-role" description:"Mapping of a role claim value to a role in the form value=role. Without mappings, claim values are used as role names"`
+		NamespaceClaim string   `long:"jwt-namespace-claim" description:"Bearer token claim holding the namespaces the client is restricted to. Without it, clients can access all namespaces"`
+	} `group:"Bearer tokens"`
+	TLS struct {
+		CertFile     string `long:"tls-cert" description:"PEM certificate file to serve HTTPS with. It is reloaded on SIGHUP"`
+		KeyFile      string `long:"tls-key" description:"PEM private key file of the certificate. It is reloaded on SIGHUP"`
+		ClientCAFile string `long:"tls-client-ca" description:"PEM file with CA certificates which client certificates must be signed by. Enables mutual TLS. It is reloaded on SIGHUP"`
+	} `group:"TLS"`
+	Logging struct {
+		Format string `long:"log-format" description:"Format of log entries" choice:"text" choice:"json" default:"text"`
+		Level  string `long:"log-level" description:"Minimum level of logged entries" choice:"trace" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info"`
+	} `group:"Logging"`
+	Tracing struct {
+		Exporter     string  `long:"trace-exporter" description:"Exporter spans are sent to" choice:"none" choice:"stdout" choice:"otlp" default:"none"`
+		OTLPEndpoint string  `long:"otlp-endpoint" description:"host:port of the OTLP/HTTP collector. Defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable or localhost:4318"`
+		OTLPInsecure bool    `long:"otlp-insecure" description:"Send spans to the OTLP collector over HTTP instead of HTTPS"`
+		SampleRatio  float64 `long:"trace-sample-ratio" description:"Ratio of traces which are sampled, unless the client's trace context decides" default:"1"`
+	} `group:"Tracing"`
+	Notifications struct {
+		Attempts  uint          `long:"notification-attempts" description:"Maximum number of attempts to deliver a notification" default:"5"`
+		Backoff   time.Duration `long:"notification-backoff" description:"Delay before retrying a failed delivery, doubled with every further retry" default:"1s"`
+		Timeout   time.Duration `long:"notification-timeout" description:"Timeout of each attempt to deliver a notification" default:"10s"`
+		PublicURL string        `long:"public-url" description:"URL the API is reachable at, e.g. https://go-work.example.com. Notifications link to runs if it is set"`
+	} `group:"Notifications"`
+	SMTP struct {
+		Host            string `long:"smtp-host" description:"SMTP server email notifications are sent through. Enables email notifications"`
+		Port            uint   `long:"smtp-port" description:"Port of the SMTP server" default:"587"`
+		Username        string `long:"smtp-username" description:"Username to authenticate with, authentication is skipped without it"`
+		Password        string `long:"smtp-password" env:"SMTP_PASSWORD" description:"Password to authenticate with"`
+		TLS             string `long:"smtp-tls" description:"How the connection to the SMTP server is secured" choice:"none" choice:"starttls" choice:"tls" default:"starttls"`
+		From            string `long:"smtp-from" description:"From address of notification emails, e.g. \"go-work <go-work@example.com>\""`
+		SubjectTemplate string `long:"smtp-subject-template" description:"File with the Go text/template of the email subject"`
+		BodyTemplate    string `long:"smtp-body-template" description:"File with the Go text/template of the email body"`
+	} `group:"Email notifications"`
+
+	APIKey APIKeyCommand `command:"api-key" description:"Manage API keys instead of serving"`
 }
 
+var logger = logging.ForPackage("main")
+
 const (
 	serverShutdownTimeout = 30 * time.Second
+	purgeInterval         = time.Hour
 	appName               = "go-work"
 )
 
 func main() {
 	opts := Options{}
-	_, err := flags.Parse(&opts)
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+	_, err := parser.Parse()
 	if err != nil {
-		log.Fatalf("Could not parse command line args: %s", err)
+		logger.Fatalf("Could not parse command line args: %s", err)
+	}
+	if err = logging.Configure(logging.Config(opts.Logging)); err != nil {
+		logger.Fatalf("Could not configure logging: %s", err)
 	}
 	dataSourceName := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
@@ -43,37 +119,240 @@ func main() {
 		appName,
 	)
 	background := context.Background()
-	var storage model.JobStorage
+	if parser.Active != nil {
+		if err = runAPIKeyCommand(background, parser.Active.Active, &opts.APIKey, dataSourceName); err != nil {
+			logger.Fatalf("Could not run api-key %s: %s", parser.Active.Active.Name, err)
+		}
+		return
+	}
+	serve(background, &opts, dataSourceName)
+}
+
+func serve(background context.Context, opts *Options, dataSourceName string) {
+	if len(opts.Intervals) == 0 {
+		logger.Fatal("At least one scheduler interval must be specified with --interval")
+	}
+	interpreters, err := shell.ParseInterpreters(opts.Interpreters)
+	if err != nil {
+		logger.Fatalf("Could not parse interpreters: %s", err)
+	}
+	executionConfig := execution.Config{
+		Interpreters:  interpreters,
+		AllowedUsers:  opts.AllowedUsers,
+		AllowedGroups: opts.AllowedGroups,
+		DefaultLimits: model.Limits(opts.DefaultLimits),
+		Environment:   opts.JobEnv,
+	}
+	if opts.CommandPolicy != "" {
+		if err := executionConfig.ReloadCommandPolicy(opts.CommandPolicy); err != nil {
+			logger.Fatalf("Could not load command policy: %s", err)
+		}
+	}
+	var keySet *auth.KeySet
+	var tokenVerifier *auth.TokenVerifier
+	if opts.Tokens.JWKS != "" {
+		if opts.Tokens.Issuer == "" || opts.Tokens.Audience == "" {
+			logger.Fatal("Bearer token issuer and audience must be specified with --jwt-issuer and --jwt-audience")
+		}
+		roleMapping, err := auth.ParseRoleMapping(opts.Tokens.Roles)
+		if err != nil {
+			logger.Fatalf("Could not parse role mappings: %s", err)
+		}
+		keySet, err = auth.LoadKeySet(background, opts.Tokens.JWKS)
+		if err != nil {
+			logger.Fatalf("Could not load JWKS: %s", err)
+		}
+		tokenVerifier = auth.NewTokenVerifier(keySet, auth.TokenConfig{
+			Issuer:         opts.Tokens.Issuer,
+			Audience:       opts.Tokens.Audience,
+			RoleClaim:      opts.Tokens.RoleClaim,
+			RoleMapping:    roleMapping,
+			NamespaceClaim: opts.Tokens.NamespaceClaim,
+		})
+	}
+	var certificateReloader *http.CertificateReloader
+	if opts.TLS.CertFile != "" || opts.TLS.KeyFile != "" || opts.TLS.ClientCAFile != "" {
+		if opts.TLS.CertFile == "" || opts.TLS.KeyFile == "" {
+			logger.Fatal("TLS certificate and key must both be specified with --tls-cert and --tls-key")
+		}
+		certificateReloader, err = http.NewCertificateReloader(http.TLSFiles(opts.TLS))
+		if err != nil {
+			logger.Fatalf("Could not load TLS certificates: %s", err)
+		}
+	}
+	shutdownTracing, err := tracing.Setup(background, tracing.Config(opts.Tracing))
+	if err != nil {
+		logger.Fatalf("Could not set up tracing: %s", err)
+	}
+	var storage model.Storage
 	storage, err = model.NewSQLJobStorage(background, "postgres", dataSourceName)
 	if err != nil {
-		log.Fatalf("Could not create job storage: %s", err)
+		logger.Fatalf("Could not create job storage: %s", err)
 	}
-	server, err := http.NewJobServer(storage, fmt.Sprintf(":%d", opts.ServerPort))
+	storage = tracing.TraceStorage(metrics.InstrumentStorage(storage))
+	if opts.Notifications.Attempts == 0 {
+		logger.Fatal("At least one notification attempt must be allowed with --notification-attempts")
+	}
+	if publicURL, err := url.Parse(opts.Notifications.PublicURL); err != nil ||
+		(opts.Notifications.PublicURL != "" && publicURL.Host == "") {
+		logger.Fatalf("Invalid --public-url \"%s\", it must be an absolute URL", opts.Notifications.PublicURL)
+	}
+	notificationConfig := notification.Config{
+		Attempts:  opts.Notifications.Attempts,
+		Backoff:   opts.Notifications.Backoff,
+		Timeout:   opts.Notifications.Timeout,
+		PublicURL: opts.Notifications.PublicURL,
+	}
+	if opts.SMTP.Host != "" {
+		notificationConfig.SMTP, err = smtpConfig(opts)
+		if err != nil {
+			logger.Fatalf("Could not configure email notifications: %s", err)
+		}
+	}
+	notifier, err := notification.New(storage, notificationConfig)
 	if err != nil {
-		log.Fatalf("Could not create job server: %s", err)
+		logger.Fatalf("Could not create notifier: %s", err)
+	}
+	schedulers := make([]*scheduler.Scheduler, 0, len(opts.Intervals))
+	for _, interval := range opts.Intervals {
+		schedulers = append(
+			schedulers,
+			scheduler.New(storage, time.Duration(interval)*time.Second, &executionConfig, notifier),
+		)
+	}
+	server, err := http.NewJobServer(
+		storage,
+		fmt.Sprintf(":%d", opts.ServerPort),
+		&executionConfig,
+		tokenVerifier,
+		schedulers,
+		notifier,
+	)
+	if err != nil {
+		logger.Fatalf("Could not create job server: %s", err)
+	}
+	if certificateReloader != nil {
+		server.TLSConfig = certificateReloader.TLSConfig()
 	}
 	cancelCtx, cancel := context.WithCancel(background)
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	wg := sync.WaitGroup{}
-	wg.Add(len(opts.Intervals))
-	for _, interval := range opts.Intervals {
-		go func(interval uint) {
+	wg.Add(len(schedulers))
+	for _, skd := range schedulers {
+		go func(skd *scheduler.Scheduler) {
+			defer wg.Done()
+			skd.Start(cancelCtx)
+		}(skd)
+	}
+	if opts.JobRetention > 0 {
+		wg.Add(1)
+		go func() {
 			defer wg.Done()
-			scheduler.New(storage, time.Duration(interval)*time.Second).Start(cancelCtx)
-		}(interval)
+			retention.New(storage, opts.JobRetention, purgeInterval).Start(cancelCtx)
+		}()
+	}
+	if opts.SLACheck > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sla.New(storage, notifier, opts.SLACheck).Start(cancelCtx)
+		}()
+	}
+	if opts.Reclaim > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reaper.New(storage, opts.ReclaimGrace, opts.Reclaim).Start(cancelCtx)
+		}()
 	}
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			log.Errorf("Listen and serve error: %s", err)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil {
+			logger.Errorf("Listen and serve error: %s", err)
 		}
 	}()
-	<-sigs
+	var metricsServer *nhttp.Server
+	if opts.MetricsPort != 0 {
+		metricsServer = http.NewMetricsServer(fmt.Sprintf(":%d", opts.MetricsPort))
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil {
+				logger.Errorf("Metrics listen and serve error: %s", err)
+			}
+		}()
+	}
+	for sig := range sigs {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		if opts.CommandPolicy != "" {
+			if err := executionConfig.ReloadCommandPolicy(opts.CommandPolicy); err != nil {
+				logger.Errorf("Failed to reload command policy, keeping the previous one: %s", err)
+			} else {
+				logger.Info("Reloaded command policy")
+			}
+		}
+		if keySet != nil {
+			if err := keySet.Reload(background); err != nil {
+				logger.Errorf("Failed to reload JWKS, keeping the previous keys: %s", err)
+			} else {
+				logger.Info("Reloaded JWKS")
+			}
+		}
+		if certificateReloader != nil {
+			if err := certificateReloader.Reload(); err != nil {
+				logger.Errorf("Failed to reload TLS certificates, keeping the previous ones: %s", err)
+			} else {
+				logger.Info("Reloaded TLS certificates")
+			}
+		}
+	}
 	cancel()
 	timeoutCtx, timeoutCancel := context.WithTimeout(background, serverShutdownTimeout)
 	defer timeoutCancel()
 	if err = server.Shutdown(timeoutCtx); err != nil {
-		log.Errorf("Failed to shutdown server: %s", err)
+		logger.Errorf("Failed to shutdown server: %s", err)
+	}
+	if metricsServer != nil {
+		if err = metricsServer.Shutdown(timeoutCtx); err != nil {
+			logger.Errorf("Failed to shutdown metrics server: %s", err)
+		}
 	}
 	wg.Wait()
+	notifier.Wait()
+	if err = shutdownTracing(timeoutCtx); err != nil {
+		logger.Errorf("Failed to flush spans: %s", err)
+	}
+}
+
+// smtpConfig builds the configuration of email notifications, reading the template files
+func smtpConfig(opts *Options) (*notification.SMTPConfig, error) {
+	config := notification.SMTPConfig{
+		Host:     opts.SMTP.Host,
+		Port:     opts.SMTP.Port,
+		Username: opts.SMTP.Username,
+		Password: opts.SMTP.Password,
+		TLS:      opts.SMTP.TLS,
+		From:     opts.SMTP.From,
+	}
+	if opts.SMTP.SubjectTemplate != "" {
+		subject, err := os.ReadFile(opts.SMTP.SubjectTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading subject template: %w", err)
+		}
+		config.SubjectTemplate = string(subject)
+	}
+	if opts.SMTP.BodyTemplate != "" {
+		body, err := os.ReadFile(opts.SMTP.BodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading body template: %w", err)
+		}
+		config.BodyTemplate = string(body)
+	}
+	return &config, nil
 }
@@ -3,23 +3,52 @@ package go_work
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	"go-work/internal/execution"
 	"go-work/internal/http"
 	"go-work/internal/model"
+	"go-work/internal/notification"
+	"go-work/internal/requestid"
 	"go-work/internal/scheduler"
+	"go-work/internal/shell"
+	"go-work/internal/sla"
+	"go-work/internal/tracing"
 	"go-work/test/data"
 	"go-work/test/url"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"io"
+	"math/big"
+	"net"
 	nhttp "net/http"
+	"net/http/httptest"
+	"net/textproto"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -32,7 +61,7 @@ type testApp struct {
 
 const timeout = time.Second * 15
 
-var deleteAllJobsQuery = "DELETE from jobs"
+var deleteAllJobsQuery = "DELETE from jobs; DELETE from quotaCharges"
 
 func TestGoWork(t *testing.T) {
 	resolveArguments()
@@ -49,69 +78,1331 @@ func TestGoWork(t *testing.T) {
 	if err != nil {
 		t.Fatal(fmt.Errorf("error opening database: %w", err))
 	}
-	storage, err := model.NewSQLJobStorage(background, "postgres", dataSourceName)
+	sqlStorage, err := model.NewSQLJobStorage(background, "postgres", dataSourceName)
 	if err != nil {
 		t.Fatal(fmt.Errorf("could not create job storage: %w", err))
 	}
-	server, err := http.NewJobServer(storage, fmt.Sprintf(":%s", os.Getenv("TEST_SERVER_PORT")))
+	storage := tracing.TraceStorage(sqlStorage)
+	// The test jobs report their execution to the ping server
+	executionConfig := execution.Config{
+		Interpreters: shell.DefaultInterpreters(),
+		Environment:  []string{"TEST_PING_SERVER_PORT"},
+	}
+	signingKey, tokenVerifier := newTestTokenVerifier(background, t)
+	smtpPort, emails := startFakeSMTPServer(t)
+	notifier, err := notification.New(storage, notification.Config{
+		Attempts: 3,
+		Backoff:  10 * time.Millisecond,
+		Timeout:  timeout,
+		SMTP: &notification.SMTPConfig{
+			Host: "127.0.0.1",
+			Port: smtpPort,
+			TLS:  notification.SMTPTLSNone,
+			From: "go-work <go-work@example.com>",
+		},
+		PublicURL: fmt.Sprintf("http://localhost:%s", os.Getenv("TEST_SERVER_PORT")),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("could not create notifier: %w", err))
+	}
+	server, err := http.NewJobServer(
+		storage,
+		fmt.Sprintf(":%s", os.Getenv("TEST_SERVER_PORT")),
+		&executionConfig,
+		tokenVerifier,
+		nil,
+		notifier,
+	)
 	if err != nil {
 		t.Fatal(fmt.Errorf("could not create job server: %w", err))
 	}
 	registerServer(background, t, server)
 
-	app := testApp{server, nhttp.DefaultClient, database}
-	t.Run("Test REST API", func(t *testing.T) {
-		t.Run("Test getting job by id", func(t *testing.T) {
+	apiKey, apiKeyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeyName := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err = storage.CreateAPIKey(background, apiKeyName, auth.Admin, nil, apiKeyHash); err != nil {
+		t.Fatal(fmt.Errorf("could not create API key: %w", err))
+	}
+	t.Cleanup(func() {
+		if err := storage.DeleteAPIKey(background, apiKeyName); err != nil {
+			t.Error(fmt.Errorf("could not delete API key: %w", err))
+		}
+	})
+
+	client := &nhttp.Client{Transport: &apiKeyTransport{apiKey}}
+	app := testApp{server, client, database}
+	t.Run("Test REST API", func(t *testing.T) {
+		t.Run("Test getting job by id", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			job, err := app.getJobById(background, existingJob.Id)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error getting created job by id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("name", existingJob.Name, job.Name, t)
+			requireEqual("crontabString", existingJob.CrontabString, job.CrontabString, t)
+			requireEqual("command", existingJob.Command, job.Command, t)
+			requireEqual("timeout", existingJob.Timeout, job.Timeout, t)
+		})
+
+		t.Run("Test creating and getting new job by name", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			job, err := app.getJobByName(background, existingJob.Name)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error getting created job by name %s: %w", existingJob.Name, err))
+			}
+			requireEqual("name", existingJob.Name, job.Name, t)
+			requireEqual("crontabString", existingJob.CrontabString, job.CrontabString, t)
+			requireEqual("command", existingJob.Command, job.Command, t)
+			requireEqual("timeout", existingJob.Timeout, job.Timeout, t)
+		})
+
+		t.Run("Test deleting job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			err := app.deleteJob(background, existingJob.Id)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error deleting job with id %d: %w", existingJob.Id, err))
+			}
+		})
+
+		t.Run("Test restoring deleted job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			if err := app.deleteJob(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error deleting job with id %d: %w", existingJob.Id, err))
+			}
+			_, err := app.getJobById(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+
+			var restoredJob model.Job
+			if err = app.post(background, url.RestoreJob(existingJob.Id), nil, &restoredJob); err != nil {
+				t.Fatal(fmt.Errorf("error restoring job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("name", restoredJob.Name, existingJob.Name, t)
+			if _, err = app.getJobById(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error getting restored job by id %d: %w", existingJob.Id, err))
+			}
+			err = app.post(background, url.RestoreJob(existingJob.Id), nil, &restoredJob)
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+
+			if err = app.deleteJob(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error deleting job with id %d: %w", existingJob.Id, err))
+			}
+			jobData := data.JobRequestData{
+				Name:          existingJob.Name,
+				CrontabString: existingJob.CrontabString,
+				Command:       existingJob.Command,
+				Timeout:       existingJob.Timeout,
+			}
+			if _, err = app.createJob(background, &jobData); err != nil {
+				t.Fatal(fmt.Errorf("error reusing the name of a deleted job: %w", err))
+			}
+			err = app.post(background, url.RestoreJob(existingJob.Id), nil, &restoredJob)
+			expectErrorStatusCode(err, nhttp.StatusConflict, t)
+		})
+
+		t.Run("Test updating and rolling back job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			jobData := data.JobRequestData{
+				Name:          existingJob.Name,
+				CrontabString: existingJob.CrontabString,
+				Command:       existingJob.Command,
+				Arguments:     existingJob.Arguments,
+				Timeout:       existingJob.Timeout + 10,
+			}
+			var updatedJob model.Job
+			if err := app.put(background, url.UpdateJob(existingJob.Id), &jobData, &updatedJob); err != nil {
+				t.Fatal(fmt.Errorf("error updating job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("revision", updatedJob.Revision, uint(2), t)
+			requireEqual("timeout", updatedJob.Timeout, existingJob.Timeout+10, t)
+
+			jobData.Name = data.InitialJobs[1].Name
+			err := app.put(background, url.UpdateJob(existingJob.Id), &jobData, &updatedJob)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+
+			var revisions []model.JobRevision
+			if err = app.get(background, url.GetRevisions(existingJob.Id), &revisions); err != nil {
+				t.Fatal(fmt.Errorf("error getting revisions of job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("number of revisions", len(revisions), 2, t)
+			requireEqual("latest revision", revisions[0].Revision, uint(2), t)
+
+			var diff struct {
+				Changes []model.FieldChange `json:"changes"`
+			}
+			if err = app.get(background, url.DiffRevisions(existingJob.Id), &diff); err != nil {
+				t.Fatal(fmt.Errorf("error comparing revisions of job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("number of changes", len(diff.Changes), 1, t)
+			requireEqual("changed field", diff.Changes[0].Field, "timeout", t)
+
+			var rolledBackJob model.Job
+			if err = app.post(background, url.RollbackJob(existingJob.Id, 1), nil, &rolledBackJob); err != nil {
+				t.Fatal(fmt.Errorf("error rolling back job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("revision", rolledBackJob.Revision, uint(3), t)
+			requireEqual("timeout", rolledBackJob.Timeout, existingJob.Timeout, t)
+		})
+
+		t.Run("Test selecting jobs by labels", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			labelledJobs := []data.JobRequestData{
+				{
+					Name:          "billing_report",
+					CrontabString: "0 * * * *",
+					Command:       "python",
+					Timeout:       10,
+					Labels:        map[string]string{"team": "billing", "env": "production"},
+				},
+				{
+					Name:          "billing_cleanup",
+					CrontabString: "0 * * * *",
+					Command:       "python",
+					Timeout:       10,
+					Labels:        map[string]string{"team": "billing", "env": "staging"},
+				},
+			}
+			ids := make([]model.JobId, len(labelledJobs))
+			for i := range labelledJobs {
+				id, err := app.createJob(background, &labelledJobs[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids[i] = id
+			}
+
+			var jobs []model.Job
+			if err := app.get(background, url.ListJobs("team=billing,env!=staging"), &jobs); err != nil {
+				t.Fatal(fmt.Errorf("error listing jobs: %w", err))
+			}
+			requireEqual("number of jobs", len(jobs), 1, t)
+			requireEqual("id", jobs[0].Id, ids[0], t)
+			if err := app.get(background, url.ListJobs(""), &jobs); err != nil {
+				t.Fatal(fmt.Errorf("error listing jobs: %w", err))
+			}
+			requireEqual("number of jobs", len(jobs), len(data.InitialJobs)+len(labelledJobs), t)
+			err := app.get(background, url.ListJobs("team in (billing"), &jobs)
+			expectErrorStatusCode(err, nhttp.StatusBadRequest, t)
+
+			if err = app.post(background, url.PauseJobs("team=billing"), nil, &jobs); err != nil {
+				t.Fatal(fmt.Errorf("error pausing jobs: %w", err))
+			}
+			requireEqual("number of paused jobs", len(jobs), len(labelledJobs), t)
+			pausedJob, err := app.getJobById(background, ids[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("paused", pausedJob.Paused, true, t)
+			if err = app.post(background, url.ResumeJobs("env=staging"), nil, &jobs); err != nil {
+				t.Fatal(fmt.Errorf("error resuming jobs: %w", err))
+			}
+			requireEqual("number of resumed jobs", len(jobs), 1, t)
+			requireEqual("paused", jobs[0].Paused, false, t)
+			err = app.post(background, url.PauseJobs(""), nil, &jobs)
+			expectErrorStatusCode(err, nhttp.StatusBadRequest, t)
+
+			if err = app.send(background, nhttp.MethodDelete, url.DeleteJobs("team in (billing),env"), nil, &jobs); err != nil {
+				t.Fatal(fmt.Errorf("error deleting jobs: %w", err))
+			}
+			requireEqual("number of deleted jobs", len(jobs), len(labelledJobs), t)
+			for _, id := range ids {
+				_, err = app.getJobById(background, id)
+				expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+			}
+		})
+
+		t.Run("Test creating already existing job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			existingJobData := data.JobRequestData{
+				Name:          existingJob.Name,
+				CrontabString: existingJob.CrontabString,
+				Command:       existingJob.Command,
+				Arguments:     existingJob.Arguments,
+				Timeout:       existingJob.Timeout,
+			}
+			_, err := app.createJob(background, &existingJobData)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+		})
+
+		t.Run("Test creating shell-mode job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			shellJobData := data.JobRequestData{
+				Name:          "shell_job",
+				CrontabString: "*/5 * * * *",
+				Mode:          model.ShellMode,
+				Script:        "echo \"$1\" | tr a-z A-Z > /dev/null",
+				Arguments:     []string{"shell"},
+				Timeout:       10,
+			}
+			id, err := app.createJob(background, &shellJobData)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error creating shell-mode job: %w", err))
+			}
+			job, err := app.getJobById(background, id)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error getting shell-mode job by id %d: %w", id, err))
+			}
+			requireEqual("mode", model.ShellMode, job.Mode, t)
+			requireEqual("script", shellJobData.Script, job.Script, t)
+			requireEqual("interpreter", shell.DefaultInterpreter, job.Interpreter, t)
+		})
+
+		t.Run("Test creating invalid shell-mode job", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			invalidJobs := []data.JobRequestData{
+				{
+					Name:          "shell_job_too_large",
+					CrontabString: "*/5 * * * *",
+					Mode:          model.ShellMode,
+					Script:        strings.Repeat("#", shell.MaxScriptSize+1),
+					Timeout:       10,
+				},
+				{
+					Name:          "shell_job_unknown_interpreter",
+					CrontabString: "*/5 * * * *",
+					Mode:          model.ShellMode,
+					Script:        "true",
+					Interpreter:   "unknown",
+					Timeout:       10,
+				},
+				{
+					Name:          "shell_job_with_command",
+					CrontabString: "*/5 * * * *",
+					Mode:          model.ShellMode,
+					Command:       "/bin/true",
+					Script:        "true",
+					Timeout:       10,
+				},
+			}
+			for i := range invalidJobs {
+				_, err := app.createJob(background, &invalidJobs[i])
+				expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+			}
+		})
+
+		t.Run("Test creating job running as a disallowed user", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			rootJobData := data.JobRequestData{
+				Name:          "root_job",
+				CrontabString: "*/5 * * * *",
+				Command:       "/bin/true",
+				Timeout:       10,
+				RunAsUser:     "root",
+			}
+			_, err := app.createJob(background, &rootJobData)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+		})
+
+		t.Run("Test command policy", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			directory := t.TempDir()
+			jobsDirectory := filepath.Join(directory, "jobs")
+			if err := os.Mkdir(jobsDirectory, 0755); err != nil {
+				t.Fatal(err)
+			}
+			script := filepath.Join(jobsDirectory, "hello.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\necho hello\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			// The symlink is inside the allowed directory, but points outside of it
+			escape := filepath.Join(jobsDirectory, "sh")
+			if err := os.Symlink("/bin/sh", escape); err != nil {
+				t.Fatal(err)
+			}
+			policyFile := filepath.Join(directory, "policy.json")
+			writePolicy := func(policy execution.CommandPolicy) {
+				policyJson, err := json.Marshal(policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = os.WriteFile(policyFile, policyJson, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err = executionConfig.ReloadCommandPolicy(policyFile); err != nil {
+					t.Fatal(fmt.Errorf("error loading command policy: %w", err))
+				}
+			}
+			writePolicy(execution.CommandPolicy{AllowedDirectories: []string{jobsDirectory}})
+			defer executionConfig.SetCommandPolicy(nil)
+
+			for _, command := range []string{
+				"hello.sh",
+				jobsDirectory + "/../jobs/hello.sh",
+				"/bin/sh",
+				escape,
+			} {
+				jobData := data.JobRequestData{
+					Name:          "disallowed_command",
+					CrontabString: "*/5 * * * *",
+					Command:       command,
+					Timeout:       10,
+				}
+				_, err := app.createJob(background, &jobData)
+				expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+			}
+			shellJobData := data.JobRequestData{
+				Name:          "disallowed_interpreter",
+				CrontabString: "*/5 * * * *",
+				Mode:          model.ShellMode,
+				Script:        "echo hello",
+				Interpreter:   "sh",
+				Timeout:       10,
+			}
+			_, err := app.createJob(background, &shellJobData)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+
+			jobData := data.JobRequestData{
+				Name:          "allowed_command",
+				CrontabString: "*/5 * * * *",
+				Command:       script,
+				Timeout:       10,
+			}
+			id, err := app.createJob(background, &jobData)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error creating job with an allowed command: %w", err))
+			}
+			job, err := storage.GetJob(background, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, err := executionConfig.Command(background, job)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error building command of allowed job: %w", err))
+			}
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatal(fmt.Errorf("error running allowed job: %w", err))
+			}
+			requireEqual("output of allowed job", string(output), "hello\n", t)
+
+			// The policy is reloaded like on SIGHUP, after which the existing job may no longer run
+			writePolicy(execution.CommandPolicy{AllowedPaths: []string{"/bin/sh"}})
+			if _, err = executionConfig.Command(background, job); err == nil {
+				t.Fatal("expected the job to be rejected at run time after the policy was reloaded")
+			}
+		})
+
+		t.Run("Test requests without a valid API key", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			unauthenticatedApp := testApp{server, nhttp.DefaultClient, database}
+			_, err := unauthenticatedApp.getJobById(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusUnauthorized, t)
+
+			invalidKeyApp := testApp{server, &nhttp.Client{Transport: &apiKeyTransport{"gw_invalid"}}, database}
+			_, err = invalidKeyApp.getJobById(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusUnauthorized, t)
+		})
+
+		t.Run("Test recording the last use of API keys", func(t *testing.T) {
+			first, err := storage.AuthenticateAPIKey(background, apiKeyHash)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error authenticating API key: %w", err))
+			}
+			if err = storage.RecordAPIKeyUse(background, first); err != nil {
+				t.Fatal(fmt.Errorf("error recording use of API key: %w", err))
+			}
+			if first.LastUsedAt == nil {
+				t.Fatal("expected the use of the API key to be recorded")
+			}
+			// Uses within a minute of the recorded one aren't written again
+			second, err := storage.AuthenticateAPIKey(background, apiKeyHash)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error authenticating API key: %w", err))
+			}
+			if err = storage.RecordAPIKeyUse(background, second); err != nil {
+				t.Fatal(fmt.Errorf("error recording use of API key: %w", err))
+			}
+			// The database stores times with microsecond precision
+			recordedAgain := second.LastUsedAt.Sub(*first.LastUsedAt)
+			requireEqual("recorded last use", recordedAgain > -time.Microsecond && recordedAgain < time.Microsecond, true, t)
+			_, err = storage.AuthenticateAPIKey(background, []byte("unknown key hash"))
+			if !errors.Is(err, model.ErrorAPIKeyNotFound) {
+				t.Fatalf("expected an unknown API key to be rejected, got %v", err)
+			}
+		})
+
+		t.Run("Test role-based access", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			viewerKeyData := map[string]string{"name": fmt.Sprintf("viewer_%d", time.Now().UnixNano()), "role": "viewer"}
+			var viewerKey struct {
+				Key string `json:"key"`
+			}
+			if err := app.post(background, url.APIKeys(), viewerKeyData, &viewerKey); err != nil {
+				t.Fatal(fmt.Errorf("error creating viewer API key: %w", err))
+			}
+			defer func() {
+				if err := app.delete(background, url.APIKey(viewerKeyData["name"])); err != nil {
+					t.Error(fmt.Errorf("error deleting viewer API key: %w", err))
+				}
+			}()
+
+			viewerApp := testApp{server, &nhttp.Client{Transport: &apiKeyTransport{viewerKey.Key}}, database}
+			existingJob := data.InitialJobs[0]
+			if _, err := viewerApp.getJobById(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error getting job by id %d as a viewer: %w", existingJob.Id, err))
+			}
+			err := viewerApp.deleteJob(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+		})
+
+		t.Run("Test namespaces", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			jobData := data.JobRequestData{
+				Name:          "cleanup",
+				CrontabString: "0 * * * *",
+				Command:       "python",
+				Timeout:       10,
+			}
+			ids := make(map[string]model.JobId)
+			for _, namespace := range []string{"team-a", "team-b"} {
+				var jobResponseId responseId
+				if err := app.post(background, url.CreateNamespacedJob(namespace), &jobData, &jobResponseId); err != nil {
+					t.Fatal(fmt.Errorf("error creating job in namespace %s: %w", namespace, err))
+				}
+				ids[namespace] = jobResponseId.Id
+			}
+			job, err := app.getJob(background, url.GetNamespacedJobByName("team-a", jobData.Name))
+			if err != nil {
+				t.Fatal(fmt.Errorf("error getting job by name in namespace team-a: %w", err))
+			}
+			requireEqual("id", job.Id, ids["team-a"], t)
+			requireEqual("namespace", job.Namespace, "team-a", t)
+			_, err = app.getJob(background, url.GetNamespacedJobById("team-b", ids["team-a"]))
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+			_, err = app.getJobById(background, ids["team-a"])
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+
+			keyData := map[string]any{
+				"name":       fmt.Sprintf("team_a_%d", time.Now().UnixNano()),
+				"role":       "admin",
+				"namespaces": []string{"team-a"},
+			}
+			var key struct {
+				Key string `json:"key"`
+			}
+			if err = app.post(background, url.APIKeys(), keyData, &key); err != nil {
+				t.Fatal(fmt.Errorf("error creating API key restricted to a namespace: %w", err))
+			}
+			defer func() {
+				if err := app.delete(background, url.APIKey(keyData["name"].(string))); err != nil {
+					t.Error(fmt.Errorf("error deleting API key: %w", err))
+				}
+			}()
+
+			restrictedApp := testApp{server, &nhttp.Client{Transport: &apiKeyTransport{key.Key}}, database}
+			if _, err = restrictedApp.getJob(background, url.GetNamespacedJobById("team-a", ids["team-a"])); err != nil {
+				t.Fatal(fmt.Errorf("error getting job in an allowed namespace: %w", err))
+			}
+			_, err = restrictedApp.getJob(background, url.GetNamespacedJobById("team-b", ids["team-b"]))
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+			_, err = restrictedApp.getJobById(background, data.InitialJobs[0].Id)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+			var keys []model.APIKey
+			err = restrictedApp.get(background, url.APIKeys(), &keys)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+		})
+
+		t.Run("Test namespace quotas", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			namespace := "limited"
+			quota := model.Quota{MaxJobs: 1, MaxConcurrentRuns: 1, MaxTimeoutPerHour: 60}
+			var usage model.QuotaUsage
+			if err := app.put(background, url.Quota(namespace), &quota, &usage); err != nil {
+				t.Fatal(fmt.Errorf("error setting quota of namespace %s: %w", namespace, err))
+			}
+			defer func() {
+				if err := app.put(background, url.Quota(namespace), &model.Quota{}, &usage); err != nil {
+					t.Error(fmt.Errorf("error removing quota of namespace %s: %w", namespace, err))
+				}
+			}()
+			requireEqual("max jobs", usage.Quota.MaxJobs, quota.MaxJobs, t)
+
+			jobData := data.JobRequestData{
+				Name:          "limited_job",
+				CrontabString: "0 * * * *",
+				Command:       "python",
+				Timeout:       quota.MaxTimeoutPerHour + 1,
+			}
+			var jobResponseId responseId
+			err := app.post(background, url.CreateNamespacedJob(namespace), &jobData, &jobResponseId)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+			jobData.Timeout = 10
+			if err = app.post(background, url.CreateNamespacedJob(namespace), &jobData, &jobResponseId); err != nil {
+				t.Fatal(fmt.Errorf("error creating job within quota: %w", err))
+			}
+			jobData.Name = "another_limited_job"
+			err = app.post(background, url.CreateNamespacedJob(namespace), &jobData, &jobResponseId)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+
+			if err = app.get(background, url.Quota(namespace), &usage); err != nil {
+				t.Fatal(fmt.Errorf("error getting quota usage of namespace %s: %w", namespace, err))
+			}
+			requireEqual("jobs", usage.Jobs, uint(1), t)
+
+			// The timeout is charged when the job is claimed, before its run starts
+			_, err = app.database.ExecContext(
+				background,
+				"UPDATE jobs SET nextExecutionTime = $1 WHERE id = $2",
+				time.Now().Add(-time.Minute),
+				jobResponseId.Id,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claimed, err := storage.MarkDueJobsRunning(background)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error claiming due jobs: %w", err))
+			}
+			claimedLimited := 0
+			for _, job := range claimed {
+				if job.Namespace == namespace {
+					claimedLimited++
+				}
+			}
+			requireEqual("claimed jobs", claimedLimited, 1, t)
+			claimedUsage, err := storage.GetQuotaUsage(background, namespace)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error getting quota usage of namespace %s: %w", namespace, err))
+			}
+			requireEqual("running jobs", claimedUsage.RunningJobs, uint(1), t)
+			requireEqual("timeout last hour", claimedUsage.TimeoutLastHour, jobData.Timeout, t)
+		})
+
+		t.Run("Test bearer token authentication", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			validClaims := func(role string) jwt.MapClaims {
+				return jwt.MapClaims{
+					"iss":   testTokenIssuer,
+					"aud":   testTokenAudience,
+					"sub":   "test_service",
+					"exp":   time.Now().Add(time.Hour).Unix(),
+					"roles": []string{"unrelated", role},
+				}
+			}
+			tokenApp := func(claims jwt.MapClaims) testApp {
+				return testApp{server, &nhttp.Client{Transport: &bearerTransport{signTestToken(t, signingKey, claims)}}, database}
+			}
+
+			readerApp := tokenApp(validClaims("jobs-reader"))
+			if _, err := readerApp.getJobById(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error getting job by id %d with a bearer token: %w", existingJob.Id, err))
+			}
+			err := readerApp.deleteJob(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+
+			expiredClaims := validClaims("jobs-admin")
+			expiredClaims["exp"] = time.Now().Add(-time.Minute).Unix()
+			wrongAudienceClaims := validClaims("jobs-admin")
+			wrongAudienceClaims["aud"] = "another_service"
+			noRoleClaims := validClaims("jobs-admin")
+			delete(noRoleClaims, "roles")
+			for _, claims := range []jwt.MapClaims{expiredClaims, wrongAudienceClaims, noRoleClaims} {
+				invalidTokenApp := tokenApp(claims)
+				_, err = invalidTokenApp.getJobById(background, existingJob.Id)
+				expectErrorStatusCode(err, nhttp.StatusUnauthorized, t)
+			}
+		})
+
+		t.Run("Test TLS with client certificates", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			dir := t.TempDir()
+			caCert, caKey := writeTestCertificate(t, dir, "ca", true, nil, nil)
+			writeTestCertificate(t, dir, "server", false, caCert, caKey)
+			clientCert, clientKey := writeTestCertificate(t, dir, "client", false, caCert, caKey)
+			certificateReloader, err := http.NewCertificateReloader(http.TLSFiles{
+				CertFile:     filepath.Join(dir, "server.pem"),
+				KeyFile:      filepath.Join(dir, "server-key.pem"),
+				ClientCAFile: filepath.Join(dir, "ca.pem"),
+			})
+			if err != nil {
+				t.Fatal(fmt.Errorf("could not load TLS certificates: %w", err))
+			}
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tlsServer := &nhttp.Server{Handler: server.Handler, TLSConfig: certificateReloader.TLSConfig()}
+			go tlsServer.ServeTLS(listener, "", "")
+			defer tlsServer.Close()
+
+			roots := x509.NewCertPool()
+			roots.AddCert(caCert)
+			requestJob := func(certificates []tls.Certificate) (*nhttp.Response, error) {
+				client := &nhttp.Client{Transport: &nhttp.Transport{
+					TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certificates},
+					DisableKeepAlives: true,
+					ForceAttemptHTTP2: true,
+				}}
+				request, _ := nhttp.NewRequestWithContext(
+					background,
+					"GET",
+					fmt.Sprintf("https://%s/api/v1/job/%d/", listener.Addr(), data.InitialJobs[0].Id),
+					nil,
+				)
+				request.Header.Set("X-API-Key", apiKey)
+				response, err := client.Do(request)
+				if err == nil {
+					response.Body.Close()
+				}
+				return response, err
+			}
+
+			if _, err = requestJob(nil); err == nil {
+				t.Fatal("expected request without a client certificate to fail")
+			}
+			clientCertificate := tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}
+			response, err := requestJob([]tls.Certificate{clientCertificate})
+			if err != nil {
+				t.Fatal(fmt.Errorf("error requesting job with a client certificate: %w", err))
+			}
+			requireEqual("status codes", response.StatusCode, nhttp.StatusOK, t)
+			requireEqual("negotiated protocol", response.Proto, "HTTP/2.0", t)
+
+			newServerCert, _ := writeTestCertificate(t, dir, "server", false, caCert, caKey)
+			if err = certificateReloader.Reload(); err != nil {
+				t.Fatal(fmt.Errorf("could not reload TLS certificates: %w", err))
+			}
+			response, err = requestJob([]tls.Certificate{clientCertificate})
+			if err != nil {
+				t.Fatal(fmt.Errorf("error requesting job after reloading certificates: %w", err))
+			}
+			requireEqual("server certificate serial numbers",
+				response.TLS.PeerCertificates[0].SerialNumber.String(),
+				newServerCert.SerialNumber.String(),
+				t,
+			)
+		})
+
+		t.Run("Test audit log of job changes", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			var triggeredJob model.Job
+			if err := app.post(background, url.TriggerJob(existingJob.Id), nil, &triggeredJob); err != nil {
+				t.Fatal(fmt.Errorf("error triggering job with id %d: %w", existingJob.Id, err))
+			}
+			if err := app.deleteJob(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error deleting job with id %d: %w", existingJob.Id, err))
+			}
+			err := app.deleteJob(background, existingJob.Id)
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+
+			var entries []model.AuditEntry
+			if err = app.get(background, url.AuditEntries(existingJob.Id, apiKeyName), &entries); err != nil {
+				t.Fatal(fmt.Errorf("error getting audit entries of job with id %d: %w", existingJob.Id, err))
+			}
+			requireEqual("number of audit entries", len(entries), 3, t)
+			deleted, triggered, created := entries[0], entries[1], entries[2]
+			requireEqual("delete action", deleted.Action, model.JobDeleted, t)
+			requireEqual("trigger action", triggered.Action, model.JobTriggered, t)
+			requireEqual("create action", created.Action, model.JobCreated, t)
+			for _, entry := range entries {
+				requireEqual("actor", entry.Actor, apiKeyName, t)
+				requireEqual("job name", entry.JobName, existingJob.Name, t)
+				if entry.RequestId == "" {
+					t.Fatalf("expected audit entry %d to have a request id", entry.Id)
+				}
+			}
+			if created.Before != nil || created.After == nil || deleted.Before == nil || deleted.After != nil {
+				t.Fatal("expected created job to have only an after snapshot and deleted job only a before snapshot")
+			}
+			if triggered.Before == nil || triggered.After == nil {
+				t.Fatal("expected triggered job to have snapshots before and after the trigger")
+			}
+			requireEqual("snapshot command", deleted.Before.Command, existingJob.Command, t)
+		})
+
+		t.Run("Test metrics endpoint", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			existingJob := data.InitialJobs[0]
+			if _, err := app.getJobById(background, existingJob.Id); err != nil {
+				t.Fatal(fmt.Errorf("error getting job by id %d: %w", existingJob.Id, err))
+			}
+			recorder := httptest.NewRecorder()
+			http.NewMetricsServer("").Handler.ServeHTTP(recorder, httptest.NewRequest(nhttp.MethodGet, "/metrics", nil))
+			requireEqual("status code", recorder.Code, nhttp.StatusOK, t)
+			metric := `gowork_http_requests_total{code="200",method="GET",route="GetJob"}`
+			if !strings.Contains(recorder.Body.String(), metric) {
+				t.Fatalf("metrics don't contain %s", metric)
+			}
+		})
+
+		t.Run("Test tracing of requests", func(t *testing.T) {
+			app.setupApp(background, t)
+			spanRecorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+
+			traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+			existingJob := data.InitialJobs[0]
+			timeoutCtx, cancel := context.WithTimeout(background, timeout)
+			defer cancel()
+			getRequest, _ := nhttp.NewRequestWithContext(timeoutCtx, "GET", url.GetJobById(existingJob.Id), nil)
+			getRequest.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+			response, err := app.client.Do(getRequest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
+				t.Fatal(err)
+			}
+
+			spanNames := map[string]bool{}
+			for _, span := range spanRecorder.Ended() {
+				if span.SpanContext().TraceID().String() == traceId {
+					spanNames[span.Name()] = true
+				}
+			}
+			if !spanNames["GetJob"] || !spanNames["storage.GetJob"] {
+				t.Fatalf("expected spans of the request and the storage call in trace %s, got %v", traceId, spanNames)
+			}
+		})
+
+		t.Run("Test health endpoints", func(t *testing.T) {
+			for _, probeUrl := range []string{url.Healthz(), url.Readyz()} {
+				response, err := nhttp.Get(probeUrl)
+				if err != nil {
+					t.Fatal(err)
+				}
+				response.Body.Close()
+				if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
+					t.Fatal(fmt.Errorf("error probing %s without credentials: %w", probeUrl, err))
+				}
+			}
+
+			response, err := nhttp.Get(url.Status())
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			requireEqual("status code", response.StatusCode, nhttp.StatusUnauthorized, t)
+			var status struct {
+				Ready    bool `json:"ready"`
+				Database struct {
+					Healthy bool `json:"healthy"`
+				} `json:"database"`
+			}
+			if err = app.get(background, url.Status(), &status); err != nil {
+				t.Fatal(fmt.Errorf("error getting status: %w", err))
+			}
+			if !status.Ready || !status.Database.Healthy {
+				t.Fatalf("expected the app to be ready with a healthy database, got %+v", status)
+			}
+		})
+
+		t.Run("Test webhook notifications", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			secret := "webhook secret"
+			received := make(chan notification.Payload, 10)
+			var failures int32 = 1
+			webhook := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Header.Get(notification.SignatureHeader) != notification.Sign(secret, body) {
+					w.WriteHeader(nhttp.StatusUnauthorized)
+					return
+				}
+				// The first delivery fails, so it is retried
+				if atomic.AddInt32(&failures, -1) >= 0 {
+					w.WriteHeader(nhttp.StatusServiceUnavailable)
+					return
+				}
+				var payload notification.Payload
+				if err := json.Unmarshal(body, &payload); err != nil {
+					w.WriteHeader(nhttp.StatusBadRequest)
+					return
+				}
+				received <- payload
+			}))
+			defer webhook.Close()
+
+			job := data.InitialJobs[0]
+			ruleData := map[string]any{
+				"jobId":               job.Id,
+				"events":              []string{"failure"},
+				"consecutiveFailures": 2,
+				"webhook":             map[string]string{"url": webhook.URL, "secret": secret},
+			}
+			var rule model.NotificationRule
+			err := app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+			ruleData["events"] = []string{"failure", "consecutiveFailures"}
+			if err = app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule); err != nil {
+				t.Fatal(fmt.Errorf("error creating notification rule: %w", err))
+			}
+			var delivery model.NotificationDelivery
+			if err = app.post(background, url.TestNotificationRule(model.DefaultNamespace, rule.Id), nil, &delivery); err != nil {
+				t.Fatal(fmt.Errorf("error testing notification rule: %w", err))
+			}
+			// Tests aren't retried, so the failing first attempt is reported
+			requireEqual("test delivery status", delivery.Status, model.DeliveryFailed, t)
+			requireEqual("test delivery attempts", delivery.Attempts, uint(1), t)
+
+			storedJob, err := storage.GetJob(background, job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := storage.StartRun(background, storedJob, 0, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			endTime := time.Now()
+			run.EndTime = &endTime
+			run.Status = model.RunFailed
+			if err = storage.FinishRun(background, run); err != nil {
+				t.Fatal(err)
+			}
+			notifier.RunFinished(background, storedJob, run)
+			notifier.Wait()
+			select {
+			case payload := <-received:
+				requireEqual("notified run", payload.Run.Id, run.Id, t)
+				requireEqual("notified events", len(payload.Events), 1, t)
+				requireEqual("notified event", payload.Events[0], model.NotifyOnFailure, t)
+			default:
+				t.Fatal("expected the webhook to receive a notification about the failed run")
+			}
+
+			var deliveries []model.NotificationDelivery
+			if err = app.get(background, url.NotificationDeliveries(model.DefaultNamespace, rule.Id), &deliveries); err != nil {
+				t.Fatal(fmt.Errorf("error getting deliveries of notification rule: %w", err))
+			}
+			requireEqual("deliveries", len(deliveries), 2, t)
+			requireEqual("delivery status", deliveries[0].Status, model.DeliverySucceeded, t)
+			requireEqual("delivery attempts", deliveries[0].Attempts, uint(1), t)
+			requireEqual("delivery run", deliveries[0].RunId, run.Id, t)
+
+			if err = app.delete(background, url.NotificationRule(model.DefaultNamespace, rule.Id)); err != nil {
+				t.Fatal(fmt.Errorf("error deleting notification rule: %w", err))
+			}
+			err = app.get(background, url.NotificationRule(model.DefaultNamespace, rule.Id), &rule)
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+		})
+
+		t.Run("Test request id propagation to webhooks", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			receivedIds := make(chan string, 1)
+			webhook := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+				receivedIds <- r.Header.Get(requestid.Header)
+			}))
+			defer webhook.Close()
+
+			ruleData := map[string]any{
+				"jobId":   data.InitialJobs[0].Id,
+				"events":  []string{"failure"},
+				"webhook": map[string]string{"url": webhook.URL},
+			}
+			var rule model.NotificationRule
+			if err := app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule); err != nil {
+				t.Fatal(fmt.Errorf("error creating notification rule: %w", err))
+			}
+			request, err := nhttp.NewRequestWithContext(
+				background,
+				"POST",
+				url.TestNotificationRule(model.DefaultNamespace, rule.Id),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("X-Request-ID", "test-request-id")
+			response, err := app.client.Do(request)
+			if err != nil {
+				t.Fatal(fmt.Errorf("error testing notification rule: %w", err))
+			}
+			defer response.Body.Close()
+			if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("returned request id", response.Header.Get(requestid.Header), "test-request-id", t)
+			select {
+			case id := <-receivedIds:
+				requireEqual("request id received by the webhook", id, "test-request-id", t)
+			default:
+				t.Fatal("expected the webhook to receive the test notification")
+			}
+		})
+
+		t.Run("Test Slack webhook notifications", func(t *testing.T) {
+			app.setupApp(background, t)
+
+			received := make(chan map[string]any, 10)
+			webhook := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+				var message map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+					w.WriteHeader(nhttp.StatusBadRequest)
+					return
+				}
+				received <- message
+			}))
+			defer webhook.Close()
+
+			job := data.InitialJobs[0]
+			ruleData := map[string]any{
+				"jobId":   job.Id,
+				"events":  []string{"failure"},
+				"webhook": map[string]string{"url": webhook.URL, "format": "teams"},
+			}
+			var rule model.NotificationRule
+			err := app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule)
+			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+			ruleData["webhook"] = map[string]string{"url": webhook.URL, "format": "slack"}
+			if err = app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule); err != nil {
+				t.Fatal(fmt.Errorf("error creating notification rule: %w", err))
+			}
+			requireEqual("webhook format", rule.Webhook.Format, model.WebhookSlack, t)
+
+			storedJob, err := storage.GetJob(background, job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := storage.StartRun(background, storedJob, 0, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			endTime := time.Now()
+			run.EndTime = &endTime
+			run.Status = model.RunFailed
+			run.OutputTail = "disk <full>\n"
+			if err = storage.FinishRun(background, run); err != nil {
+				t.Fatal(err)
+			}
+			notifier.RunFinished(background, storedJob, run)
+			notifier.Wait()
+			var message map[string]any
+			select {
+			case message = <-received:
+			default:
+				t.Fatal("expected the webhook to receive a message about the failed run")
+			}
+			attachments, ok := message["attachments"].([]any)
+			if !ok || len(attachments) != 1 {
+				t.Fatalf("expected a message with one attachment, got %v", message)
+			}
+			attachment := attachments[0].(map[string]any)
+			link, _ := attachment["title_link"].(string)
+			requireEqual("message link", link, url.Run(model.DefaultNamespace, job.Id, run.Id), t)
+			if !strings.Contains(attachment["text"].(string), "disk &lt;full&gt;") {
+				t.Fatalf("expected the message to contain the escaped output, got %v", attachment["text"])
+			}
+
+			var linkedRun model.Run
+			if err = app.get(background, link, &linkedRun); err != nil {
+				t.Fatal(fmt.Errorf("error getting the linked run: %w", err))
+			}
+			requireEqual("linked run", linkedRun.Id, run.Id, t)
+			requireEqual("linked run status", linkedRun.Status, model.RunFailed, t)
+			err = app.get(background, url.Run(model.DefaultNamespace, job.Id, run.Id+1), &linkedRun)
+			expectErrorStatusCode(err, nhttp.StatusNotFound, t)
+		})
+
+		t.Run("Test SLA violations", func(t *testing.T) {
 			app.setupApp(background, t)
 
-			existingJob := data.InitialJobs[0]
-			job, err := app.getJobById(background, existingJob.Id)
+			received := make(chan notification.Payload, 10)
+			webhook := httptest.NewServer(nhttp.HandlerFunc(func(w nhttp.ResponseWriter, r *nhttp.Request) {
+				var payload notification.Payload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					w.WriteHeader(nhttp.StatusBadRequest)
+					return
+				}
+				received <- payload
+			}))
+			defer webhook.Close()
+
+			jobData := data.JobRequestData{
+				Name:               "nightly_backup",
+				CrontabString:      "0 * * * *",
+				Command:            "python",
+				Timeout:            10,
+				MaxSuccessInterval: 3600,
+			}
+			var jobResponseId responseId
+			if err := app.post(background, url.CreateJob(), &jobData, &jobResponseId); err != nil {
+				t.Fatal(fmt.Errorf("error creating job: %w", err))
+			}
+			ruleData := map[string]any{
+				"jobId":   jobResponseId.Id,
+				"events":  []string{"slaViolation"},
+				"webhook": map[string]string{"url": webhook.URL},
+			}
+			var rule model.NotificationRule
+			if err := app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule); err != nil {
+				t.Fatal(fmt.Errorf("error creating notification rule: %w", err))
+			}
+
+			// New jobs are measured from their creation
+			var violations []model.SLAViolation
+			if err := app.get(background, url.SLAViolations(model.DefaultNamespace), &violations); err != nil {
+				t.Fatal(fmt.Errorf("error listing SLA violations: %w", err))
+			}
+			requireEqual("violations of a new job", len(violations), 0, t)
+
+			job, err := storage.GetJob(background, jobResponseId.Id)
 			if err != nil {
-				t.Fatal(fmt.Errorf("error getting created job by id %d: %w", existingJob.Id, err))
+				t.Fatal(err)
 			}
-			requireEqual("name", existingJob.Name, job.Name, t)
-			requireEqual("crontabString", existingJob.CrontabString, job.CrontabString, t)
-			requireEqual("command", existingJob.Command, job.Command, t)
-			requireEqual("timeout", existingJob.Timeout, job.Timeout, t)
+			finishRun := func(startTime time.Time, endTime time.Time) {
+				run, err := storage.StartRun(background, job, 0, startTime)
+				if err != nil {
+					t.Fatal(err)
+				}
+				run.EndTime = &endTime
+				run.Status = model.RunSucceeded
+				if err = storage.FinishRun(background, run); err != nil {
+					t.Fatal(err)
+				}
+			}
+			finishRun(time.Now().Add(-3*time.Hour), time.Now().Add(-2*time.Hour))
+			if err = app.get(background, url.SLAViolations(model.DefaultNamespace), &violations); err != nil {
+				t.Fatal(fmt.Errorf("error listing SLA violations: %w", err))
+			}
+			requireEqual("violations", len(violations), 1, t)
+			requireEqual("violating job", violations[0].JobId, job.Id, t)
+			if violations[0].LastSuccess == nil || violations[0].AlertedAt != nil {
+				t.Fatalf("expected a violation with a last success which wasn't alerted about, got %+v", violations[0])
+			}
+
+			checkCtx, cancelCheck := context.WithCancel(background)
+			defer cancelCheck()
+			go sla.New(storage, notifier, time.Hour).Start(checkCtx)
+			select {
+			case payload := <-received:
+				requireEqual("notified event", payload.Events[0], model.NotifyOnSLAViolation, t)
+				requireEqual("notified job", payload.SLAViolation.JobId, job.Id, t)
+			case <-time.After(timeout):
+				t.Fatal("expected the webhook to receive a notification about the SLA violation")
+			}
+			cancelCheck()
+			newViolations, err := storage.MarkSLAViolations(background)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("violations alerted about again", len(newViolations), 0, t)
+
+			finishRun(time.Now(), time.Now())
+			if err = app.get(background, url.SLAViolations(model.DefaultNamespace), &violations); err != nil {
+				t.Fatal(fmt.Errorf("error listing SLA violations: %w", err))
+			}
+			requireEqual("violations after a success", len(violations), 0, t)
 		})
 
-		t.Run("Test creating and getting new job by name", func(t *testing.T) {
+		t.Run("Test reclaiming lost runs", func(t *testing.T) {
 			app.setupApp(background, t)
 
-			existingJob := data.InitialJobs[0]
-			job, err := app.getJobByName(background, existingJob.Name)
+			jobData := data.JobRequestData{
+				Name:          "hourly_report",
+				CrontabString: "0 * * * *",
+				Command:       "python",
+				Timeout:       10,
+			}
+			var jobResponseId responseId
+			if err := app.post(background, url.CreateJob(), &jobData, &jobResponseId); err != nil {
+				t.Fatal(fmt.Errorf("error creating job: %w", err))
+			}
+			job, err := storage.GetJob(background, jobResponseId.Id)
 			if err != nil {
-				t.Fatal(fmt.Errorf("error getting created job by name %s: %w", existingJob.Name, err))
+				t.Fatal(err)
 			}
-			requireEqual("name", existingJob.Name, job.Name, t)
-			requireEqual("crontabString", existingJob.CrontabString, job.CrontabString, t)
-			requireEqual("command", existingJob.Command, job.Command, t)
-			requireEqual("timeout", existingJob.Timeout, job.Timeout, t)
+			instance := model.SchedulerInstance{Hostname: "test", PingIntervalSeconds: 1, Version: "test"}
+			if err = storage.RegisterScheduler(background, &instance); err != nil {
+				t.Fatal(err)
+			}
+			defer storage.StopScheduler(background, instance.Id)
+
+			// The job was claimed an hour ago by a scheduler which is still live
+			claimedAt := time.Now().Add(-time.Hour)
+			_, err = app.database.ExecContext(
+				background,
+				"UPDATE jobs SET running = true, claimedAt = $1 WHERE id = $2",
+				claimedAt,
+				job.Id,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := storage.StartRun(background, job, instance.Id, claimedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reclaimed, err := storage.ReclaimJobs(background, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("jobs reclaimed from a live scheduler", len(reclaimed), 0, t)
+
+			if err = storage.StopScheduler(background, instance.Id); err != nil {
+				t.Fatal(err)
+			}
+			reclaimed, err = storage.ReclaimJobs(background, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("reclaimed jobs", len(reclaimed), 1, t)
+			requireEqual("reclaimed job", reclaimed[0].Job.Id, job.Id, t)
+			requireEqual("lost runs", len(reclaimed[0].LostRuns), 1, t)
+			requireEqual("lost run", reclaimed[0].LostRuns[0], run.Id, t)
+
+			var lostRun model.Run
+			if err = app.get(background, url.Run(model.DefaultNamespace, job.Id, run.Id), &lostRun); err != nil {
+				t.Fatal(fmt.Errorf("error getting run: %w", err))
+			}
+			requireEqual("status of the lost run", lostRun.Status, model.RunLost, t)
+			var running bool
+			if err = app.database.QueryRowContext(background, "SELECT running FROM jobs WHERE id = $1", job.Id).Scan(&running); err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("reclaimed job running", running, false, t)
+
+			reclaimed, err = storage.ReclaimJobs(background, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("jobs reclaimed again", len(reclaimed), 0, t)
 		})
 
-		t.Run("Test deleting job", func(t *testing.T) {
+		t.Run("Test restarting storage over a job left running", func(t *testing.T) {
 			app.setupApp(background, t)
 
-			existingJob := data.InitialJobs[0]
-			err := app.deleteJob(background, existingJob.Id)
+			job, err := storage.GetJob(background, data.InitialJobs[0].Id)
 			if err != nil {
-				t.Fatal(fmt.Errorf("error deleting job with id %d: %w", existingJob.Id, err))
+				t.Fatal(err)
+			}
+			instance := model.SchedulerInstance{Hostname: "test", PingIntervalSeconds: 1, Version: "test"}
+			if err = storage.RegisterScheduler(background, &instance); err != nil {
+				t.Fatal(err)
+			}
+			// The job was claimed an hour ago by a scheduler whose process crashed
+			claimedAt := time.Now().Add(-time.Hour)
+			_, err = app.database.ExecContext(
+				background,
+				"UPDATE jobs SET running = true, claimedAt = $1 WHERE id = $2",
+				claimedAt,
+				job.Id,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := storage.StartRun(background, job, instance.Id, claimedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = storage.StopScheduler(background, instance.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			restarted, err := model.NewSQLJobStorage(background, "postgres", dataSourceName)
+			if err != nil {
+				t.Fatal(fmt.Errorf("could not restart job storage: %w", err))
+			}
+			if err = restarted.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var running bool
+			if err = app.database.QueryRowContext(background, "SELECT running FROM jobs WHERE id = $1", job.Id).Scan(&running); err != nil {
+				t.Fatal(err)
+			}
+			requireEqual("job running after a restart", running, true, t)
+
+			reclaimed, err := storage.ReclaimJobs(background, time.Minute)
+			if err != nil {
+				t.Fatal(err)
 			}
+			requireEqual("reclaimed jobs", len(reclaimed), 1, t)
+			requireEqual("lost runs", len(reclaimed[0].LostRuns), 1, t)
+			requireEqual("lost run", reclaimed[0].LostRuns[0], run.Id, t)
 		})
 
-		t.Run("Test creating already existing job", func(t *testing.T) {
+		t.Run("Test email notifications", func(t *testing.T) {
 			app.setupApp(background, t)
 
-			existingJob := data.InitialJobs[0]
-			existingJobData := data.JobRequestData{
-				Name:          existingJob.Name,
-				CrontabString: existingJob.CrontabString,
-				Command:       existingJob.Command,
-				Arguments:     existingJob.Arguments,
-				Timeout:       existingJob.Timeout,
+			job := data.InitialJobs[0]
+			ruleData := map[string]any{
+				"jobId":   job.Id,
+				"events":  []string{"failure"},
+				"email":   map[string]any{"to": []string{"ops@example.com"}},
+				"webhook": map[string]string{"url": "http://localhost/hook"},
 			}
-			_, err := app.createJob(background, &existingJobData)
+			var rule model.NotificationRule
+			err := app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule)
 			expectErrorStatusCode(err, nhttp.StatusUnprocessableEntity, t)
+			delete(ruleData, "webhook")
+			if err = app.post(background, url.NotificationRules(model.DefaultNamespace), &ruleData, &rule); err != nil {
+				t.Fatal(fmt.Errorf("error creating notification rule: %w", err))
+			}
+			var delivery model.NotificationDelivery
+			if err = app.post(background, url.TestNotificationRule(model.DefaultNamespace, rule.Id), nil, &delivery); err != nil {
+				t.Fatal(fmt.Errorf("error testing notification rule: %w", err))
+			}
+			requireEqual("test delivery status", delivery.Status, model.DeliverySucceeded, t)
+			message := <-emails
+			if !strings.Contains(message, "Subject: [go-work] Test notification of rule") {
+				t.Fatalf("expected a test email, got %s", message)
+			}
+
+			storedJob, err := storage.GetJob(background, job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run, err := storage.StartRun(background, storedJob, 0, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			endTime := time.Now()
+			exitCode := 3
+			run.EndTime = &endTime
+			run.Status = model.RunFailed
+			run.ExitCode = &exitCode
+			run.OutputTail = "connecting to backup host\nbackup host unreachable\n"
+			if err = storage.FinishRun(background, run); err != nil {
+				t.Fatal(err)
+			}
+			notifier.RunFinished(background, storedJob, run)
+			notifier.Wait()
+			select {
+			case message = <-emails:
+			default:
+				t.Fatal("expected an email about the failed run")
+			}
+			for _, expected := range []string{
+				"To: ops@example.com",
+				fmt.Sprintf("Subject: [go-work] %s/%s: failure", model.DefaultNamespace, storedJob.Name),
+				"Exit code:  3",
+				"backup host unreachable",
+			} {
+				if !strings.Contains(message, expected) {
+					t.Fatalf("expected the email to contain %q, got %s", expected, message)
+				}
+			}
 		})
 
 		t.Run("Test getting nonexistent job", func(t *testing.T) {
@@ -137,7 +1428,7 @@ func TestGoWork(t *testing.T) {
 			app.setupApp(background, t)
 			cancelCtx, cancel := context.WithCancel(background)
 			defer cancel()
-			schd := scheduler.New(storage, 1)
+			schd := scheduler.New(storage, 1, &executionConfig, nil)
 			go func() {
 				schd.Start(cancelCtx)
 			}()
@@ -145,12 +1436,229 @@ func TestGoWork(t *testing.T) {
 			if err := executionData.ValidateExecutionData(); err != nil {
 				t.Fatal(fmt.Errorf("error validating execution of tasks: %w", err))
 			}
+
+			job := data.InitialJobs[0]
+			var runs []model.Run
+			if err := app.get(background, url.GetRuns(job.Id), &runs); err != nil {
+				t.Fatal(fmt.Errorf("error getting runs of job with id %d: %w", job.Id, err))
+			}
+			if len(runs) < 2 {
+				t.Fatalf("expected at least 2 runs of job with id %d, got %d", job.Id, len(runs))
+			}
+			var schedulers []model.SchedulerInstance
+			if err := app.get(background, url.ListSchedulers(), &schedulers); err != nil {
+				t.Fatal(fmt.Errorf("error listing schedulers: %w", err))
+			}
+			if len(schedulers) == 0 || !schedulers[0].Live {
+				t.Fatalf("expected the running scheduler to be registered and live, got %+v", schedulers)
+			}
+			recorder := httptest.NewRecorder()
+			http.NewMetricsServer("").Handler.ServeHTTP(recorder, httptest.NewRequest(nhttp.MethodGet, "/metrics", nil))
+			metric := fmt.Sprintf(`gowork_running_jobs{scheduler="%d"}`, schedulers[0].Id)
+			if !strings.Contains(recorder.Body.String(), metric) {
+				t.Fatalf("expected running jobs to be labeled with the scheduler's id, metrics don't contain %s", metric)
+			}
+			for _, run := range runs {
+				requireEqual("run job revision", run.JobRevision, uint(1), t)
+				requireEqual("run scheduler id", run.SchedulerId, schedulers[0].Id, t)
+				if run.Status == model.RunRunning {
+					continue
+				}
+				if run.Status != model.RunSucceeded || run.Usage == nil {
+					t.Fatalf("expected run %d to have succeeded and recorded its resource usage", run.Id)
+				}
+			}
+			var stats model.JobStats
+			if err := app.get(background, url.GetJobStats(job.Id), &stats); err != nil {
+				t.Fatal(fmt.Errorf("error getting stats of job with id %d: %w", job.Id, err))
+			}
+			if stats.SucceededRuns < 2 || stats.DurationP95Seconds == nil {
+				t.Fatalf("expected stats of job with id %d to cover its runs, got %+v", job.Id, stats)
+			}
+		})
+		t.Run("Test triggering jobs and cancelling runs", func(t *testing.T) {
+			app.clearDatabase(background, t)
+
+			operatorKeyData := map[string]string{"name": fmt.Sprintf("operator_%d", time.Now().UnixNano()), "role": "operator"}
+			var operatorKey struct {
+				Key string `json:"key"`
+			}
+			if err := app.post(background, url.APIKeys(), operatorKeyData, &operatorKey); err != nil {
+				t.Fatal(fmt.Errorf("error creating operator API key: %w", err))
+			}
+			defer func() {
+				if err := app.delete(background, url.APIKey(operatorKeyData["name"])); err != nil {
+					t.Error(fmt.Errorf("error deleting operator API key: %w", err))
+				}
+			}()
+			operatorApp := testApp{server, &nhttp.Client{Transport: &apiKeyTransport{operatorKey.Key}}, database}
+
+			id, err := app.createJob(background, &data.JobRequestData{
+				Name:          "yearly_sleep",
+				CrontabString: "0 0 1 1 *",
+				Command:       "sleep",
+				Arguments:     []string{"60"},
+				Timeout:       120,
+			})
+			if err != nil {
+				t.Fatal(fmt.Errorf("error creating job: %w", err))
+			}
+			err = operatorApp.deleteJob(background, id)
+			expectErrorStatusCode(err, nhttp.StatusForbidden, t)
+
+			if _, err = app.database.ExecContext(background, "UPDATE jobs SET paused = true WHERE id = $1", id); err != nil {
+				t.Fatal(err)
+			}
+			var triggered model.Job
+			err = operatorApp.post(background, url.TriggerJob(id), nil, &triggered)
+			expectErrorStatusCode(err, nhttp.StatusConflict, t)
+			if _, err = app.database.ExecContext(background, "UPDATE jobs SET paused = false WHERE id = $1", id); err != nil {
+				t.Fatal(err)
+			}
+			if err = operatorApp.post(background, url.TriggerJob(id), nil, &triggered); err != nil {
+				t.Fatal(fmt.Errorf("error triggering job as an operator: %w", err))
+			}
+			requireEqual("triggered job", triggered.Id, id, t)
+
+			cancelCtx, cancel := context.WithCancel(background)
+			defer cancel()
+			schd := scheduler.New(storage, time.Second, &executionConfig, nil)
+			go schd.Start(cancelCtx)
+
+			var run model.Run
+			for deadline := time.Now().Add(timeout); ; time.Sleep(100 * time.Millisecond) {
+				runs, err := storage.GetRuns(background, id, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(runs) > 0 {
+					run = *runs[0]
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("expected the triggered job to run")
+				}
+			}
+			err = operatorApp.post(background, url.TriggerJob(id), nil, &triggered)
+			expectErrorStatusCode(err, nhttp.StatusConflict, t)
+
+			var cancelledRun model.Run
+			if err = operatorApp.post(background, url.CancelRun(model.DefaultNamespace, id, run.Id), nil, &cancelledRun); err != nil {
+				t.Fatal(fmt.Errorf("error cancelling run as an operator: %w", err))
+			}
+			if cancelledRun.CancelRequestedAt == nil {
+				t.Fatal("expected the run to record the cancellation request")
+			}
+			// Schedulers look for cancellation requests with their heartbeats
+			for deadline := time.Now().Add(2*model.SchedulerHeartbeatInterval + timeout); ; time.Sleep(100 * time.Millisecond) {
+				if err = app.get(background, url.Run(model.DefaultNamespace, id, run.Id), &cancelledRun); err != nil {
+					t.Fatal(fmt.Errorf("error getting run: %w", err))
+				}
+				if cancelledRun.Status != model.RunRunning {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("expected the run to be cancelled")
+				}
+			}
+			requireEqual("status of the cancelled run", cancelledRun.Status, model.RunCancelled, t)
+			err = operatorApp.post(background, url.CancelRun(model.DefaultNamespace, id, run.Id), nil, &cancelledRun)
+			expectErrorStatusCode(err, nhttp.StatusConflict, t)
+		})
+
+		t.Run("Test resource limits", func(t *testing.T) {
+			// Limits are applied by re-executing the running binary, which is the test binary here
+			runLimited := func(limits model.Limits, command string, arguments ...string) (*exec.Cmd, string, error) {
+				job := &model.Job{Command: command, Arguments: arguments, Timeout: 10, Limits: limits}
+				cmd, err := executionConfig.Command(background, job)
+				if err != nil {
+					t.Fatal(fmt.Errorf("error building command with limits: %w", err))
+				}
+				output, err := cmd.CombinedOutput()
+				return cmd, string(output), err
+			}
+
+			limits := model.Limits{CPUSeconds: 5, OpenFiles: 16, Processes: 64}
+			_, output, err := runLimited(limits, "cat", "/proc/self/limits")
+			if err != nil {
+				t.Fatal(fmt.Errorf("error reading limits of process: %w", err))
+			}
+			for _, pattern := range []string{
+				`Max cpu time\s+5\s+6\s`,
+				`Max open files\s+16\s+16\s`,
+				`Max processes\s+64\s+64\s`,
+			} {
+				if !regexp.MustCompile(pattern).MatchString(output) {
+					t.Fatalf("expected process limits to match %s, got:\n%s", pattern, output)
+				}
+			}
+
+			cpuLimits := model.Limits{CPUSeconds: 1}
+			cmd, _, err := runLimited(cpuLimits, "sh", "-c", "while :; do :; done")
+			if err == nil {
+				t.Fatal("expected the process to be terminated after exceeding its CPU limit")
+			}
+			requireEqual(
+				"termination cause",
+				execution.TerminationCause(cmd.ProcessState, cpuLimits),
+				model.TerminatedByCPULimit,
+				t,
+			)
+			if usage := execution.ResourceUsage(cmd.ProcessState); usage.UserCPUSeconds+usage.SystemCPUSeconds < 0.9 {
+				t.Fatalf("expected the process to have used its CPU time, got %+v", usage)
+			}
+
+			// Exceeding the other limits makes calls fail, which isn't attributed to the limits
+			fileLimits := model.Limits{OpenFiles: 5}
+			cmd, output, err = runLimited(fileLimits, "sh", "-c", "exec 3</dev/null 4</dev/null 5</dev/null")
+			if err == nil || !strings.Contains(output, "Too many open files") {
+				t.Fatalf("expected opening files beyond the limit to fail, got %v: %s", err, output)
+			}
+			requireEqual("termination cause", execution.TerminationCause(cmd.ProcessState, fileLimits), "", t)
+
+			// A crash isn't attributed to the address space limit
+			crashLimits := model.Limits{AddressSpace: 1 << 32}
+			cmd, _, err = runLimited(crashLimits, "sh", "-c", "kill -SEGV $$")
+			if err == nil {
+				t.Fatal("expected the crashing process to fail")
+			}
+			requireEqual("termination cause", execution.TerminationCause(cmd.ProcessState, crashLimits), "", t)
+		})
+
+		t.Run("Test environment of jobs", func(t *testing.T) {
+			t.Setenv("POSTGRES_APP_PASSWORD", "secret")
+			t.Setenv("GO_WORK_TEST_VARIABLE", "passed")
+			environmentConfig := execution.Config{Environment: []string{"GO_WORK_TEST_VARIABLE"}}
+			cmd, err := environmentConfig.Command(background, &model.Job{Command: "env", Timeout: 10})
+			if err != nil {
+				t.Fatal(fmt.Errorf("error building command: %w", err))
+			}
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatal(fmt.Errorf("error running command: %w", err))
+			}
+			environment := strings.Split(strings.TrimSpace(string(output)), "\n")
+			for _, variable := range environment {
+				if strings.HasPrefix(variable, "POSTGRES_APP_PASSWORD=") {
+					t.Fatalf("expected the app's secrets not to be passed to jobs, got:\n%s", output)
+				}
+			}
+			for _, variable := range []string{"PATH=" + os.Getenv("PATH"), "GO_WORK_TEST_VARIABLE=passed"} {
+				found := false
+				for _, v := range environment {
+					found = found || v == variable
+				}
+				if !found {
+					t.Fatalf("expected the environment of jobs to contain %s, got:\n%s", variable, output)
+				}
+			}
 		})
+
 		t.Run("Test execution of initial jobs while modifying database", func(t *testing.T) {
 			app.setupApp(background, t)
 			cancelCtx, cancel := context.WithCancel(background)
 			defer cancel()
-			schd := scheduler.New(storage, 1)
+			schd := scheduler.New(storage, 1, &executionConfig, nil)
 			go func() {
 				schd.Start(cancelCtx)
 			}()
@@ -205,6 +1713,57 @@ func getPingServer(executionData *data.ExecutionData) *nhttp.Server {
 	return &nhttp.Server{Addr: fmt.Sprintf(":%s", os.Getenv("TEST_PING_SERVER_PORT")), Handler: pingRouter}
 }
 
+// startFakeSMTPServer accepts every message sent to it on a local port, passing them to the returned channel
+func startFakeSMTPServer(t *testing.T) (uint, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("could not start fake SMTP server: %w", err))
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSMTP(conn, messages)
+		}
+	}()
+	return uint(listener.Addr().(*net.TCPAddr).Port), messages
+}
+
+func serveFakeSMTP(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "DATA":
+			text.PrintfLine("354 Start mail input")
+			message, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			messages <- string(message)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
 func registerServer(ctx context.Context, t *testing.T, server *nhttp.Server) {
 	go func() {
 		if err := server.ListenAndServe(); err != nil {
@@ -256,6 +1815,131 @@ func (ta *testApp) clearDatabase(ctx context.Context, t *testing.T) {
 	}
 }
 
+type apiKeyTransport struct {
+	key string
+}
+
+func (at *apiKeyTransport) RoundTrip(req *nhttp.Request) (*nhttp.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-API-Key", at.key)
+	return nhttp.DefaultTransport.RoundTrip(req)
+}
+
+type bearerTransport struct {
+	token string
+}
+
+func (bt *bearerTransport) RoundTrip(req *nhttp.Request) (*nhttp.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+bt.token)
+	return nhttp.DefaultTransport.RoundTrip(req)
+}
+
+const (
+	testTokenIssuer   = "https://idp.example.com"
+	testTokenAudience = "go-work"
+	testTokenKeyId    = "test_key"
+)
+
+// newTestTokenVerifier creates a token verifier using a static JWKS file with a freshly generated key
+func newTestTokenVerifier(ctx context.Context, t *testing.T) (*rsa.PrivateKey, *auth.TokenVerifier) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testTokenKeyId,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(jwksPath, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keySet, err := auth.LoadKeySet(ctx, jwksPath)
+	if err != nil {
+		t.Fatal(fmt.Errorf("could not load JWKS: %w", err))
+	}
+	return signingKey, auth.NewTokenVerifier(keySet, auth.TokenConfig{
+		Issuer:      testTokenIssuer,
+		Audience:    testTokenAudience,
+		RoleClaim:   "roles",
+		RoleMapping: map[string]auth.Role{"jobs-reader": auth.Viewer, "jobs-admin": auth.Admin},
+	})
+}
+
+// writeTestCertificate generates a certificate for 127.0.0.1 and writes it to dir as <name>.pem and <name>-key.pem.
+// The certificate is self-signed if parent is nil
+func writeTestCertificate(
+	t *testing.T,
+	dir string,
+	name string,
+	isCA bool,
+	parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err = os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
+
+func signTestToken(t *testing.T, signingKey *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testTokenKeyId
+	signed, err := token.SignedString(signingKey)
+	if err != nil {
+		t.Fatal(fmt.Errorf("could not sign token: %w", err))
+	}
+	return signed
+}
+
 type responseId struct {
 	Id model.JobId `json:"id"`
 }
@@ -308,57 +1992,73 @@ func decodeResponse(response *nhttp.Response, v any) error {
 	return nil
 }
 
-func (ta *testApp) createJob(ctx context.Context, jobData *data.JobRequestData) (model.JobId, error) {
-	jobDataJson, err := json.Marshal(jobData)
+func (ta *testApp) post(ctx context.Context, url string, requestData any, v any) error {
+	return ta.send(ctx, "POST", url, requestData, v)
+}
+
+func (ta *testApp) put(ctx context.Context, url string, requestData any, v any) error {
+	return ta.send(ctx, "PUT", url, requestData, v)
+}
+
+func (ta *testApp) send(ctx context.Context, method string, url string, requestData any, v any) error {
+	requestJson, err := json.Marshal(requestData)
 	if err != nil {
-		return 0, fmt.Errorf("error marshalling job data: %w", err)
+		return fmt.Errorf("error marshalling request data: %w", err)
 	}
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	createJobRequest, _ := nhttp.NewRequestWithContext(
+	request, _ := nhttp.NewRequestWithContext(
 		timeoutCtx,
-		"POST",
-		url.CreateJob(),
-		bytes.NewReader(jobDataJson),
+		method,
+		url,
+		bytes.NewReader(requestJson),
 	)
-	createJobRequest.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Type", "application/json")
 
-	response, err := ta.client.Do(createJobRequest)
+	response, err := ta.client.Do(request)
 	if err != nil {
-		return 0, fmt.Errorf("error getting response while creating job %v: %w", jobData, err)
+		return fmt.Errorf("error getting response while sending %s to url \"%s\": %w", method, url, err)
 	}
 	defer response.Body.Close()
 	if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
-		return 0, fmt.Errorf("error creating job %v: %w", jobData, err)
+		return fmt.Errorf("error sending %s to url %s: %w", method, url, err)
 	}
+	return decodeResponse(response, v)
+}
+
+func (ta *testApp) createJob(ctx context.Context, jobData *data.JobRequestData) (model.JobId, error) {
 	var jobResponseId responseId
-	if err = decodeResponse(response, &jobResponseId); err != nil {
-		return 0, err
+	if err := ta.post(ctx, url.CreateJob(), jobData, &jobResponseId); err != nil {
+		return 0, fmt.Errorf("error creating job %v: %w", jobData, err)
 	}
 	return jobResponseId.Id, nil
 }
 
-func (ta *testApp) getJob(ctx context.Context, url string) (*model.Job, error) {
+func (ta *testApp) get(ctx context.Context, url string, v any) error {
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	getJobRequest, _ := nhttp.NewRequestWithContext(
+	getRequest, _ := nhttp.NewRequestWithContext(
 		timeoutCtx,
 		"GET",
 		url,
 		nil,
 	)
 
-	response, err := ta.client.Do(getJobRequest)
+	response, err := ta.client.Do(getRequest)
 	if err != nil {
-		return nil, fmt.Errorf("error getting response while getting job by url \"%s\": %w", url, err)
+		return fmt.Errorf("error getting response while getting url \"%s\": %w", url, err)
 	}
 	defer response.Body.Close()
 	if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
-		return nil, fmt.Errorf("error getting job by url %s: %w", url, err)
+		return fmt.Errorf("error getting url %s: %w", url, err)
 	}
+	return decodeResponse(response, v)
+}
+
+func (ta *testApp) getJob(ctx context.Context, url string) (*model.Job, error) {
 	var responseJob model.Job
-	if err = decodeResponse(response, &responseJob); err != nil {
+	if err := ta.get(ctx, url, &responseJob); err != nil {
 		return nil, err
 	}
 	return &responseJob, nil
@@ -372,19 +2072,23 @@ func (ta *testApp) getJobByName(ctx context.Context, name string) (*model.Job, e
 	return ta.getJob(ctx, url.GetJobByName(name))
 }
 
-func (ta *testApp) deleteJob(ctx context.Context, id model.JobId) error {
+func (ta *testApp) delete(ctx context.Context, url string) error {
 	deleteRequest, _ := nhttp.NewRequestWithContext(
 		ctx,
 		"DELETE",
-		url.DeleteJob(id),
+		url,
 		nil,
 	)
 	response, err := ta.client.Do(deleteRequest)
 	if err != nil {
-		return fmt.Errorf("error getting response while deleting job with id %d: %w", id, err)
+		return fmt.Errorf("error getting response while deleting url \"%s\": %w", url, err)
 	}
 	defer response.Body.Close()
-	if err = checkStatusCode(response, nhttp.StatusOK); err != nil {
+	return checkStatusCode(response, nhttp.StatusOK)
+}
+
+func (ta *testApp) deleteJob(ctx context.Context, id model.JobId) error {
+	if err := ta.delete(ctx, url.DeleteJob(id)); err != nil {
 		return fmt.Errorf("error deleting job with id %d: %w", id, err)
 	}
 	return nil
@@ -396,6 +2100,7 @@ func (ta *testApp) createJobs(ctx context.Context, t *testing.T) {
 		jobData := data.JobRequestData{
 			Name:          job.Name,
 			CrontabString: job.CrontabString,
+			Mode:          job.Mode,
 			Command:       job.Command,
 			Arguments:     job.Arguments,
 			Timeout:       job.Timeout,
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+const (
+	apiKeyPrefix = "gw_"
+	apiKeyBytes  = 32
+)
+
+// GenerateAPIKey returns a new random API key along with its hash. Only the hash is
+// meant to be stored, the key itself is shown to its owner once
+func GenerateAPIKey() (string, []byte, error) {
+	keyBytes := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return "", nil, fmt.Errorf("failed generating API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(keyBytes)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes an API key for storage and lookup. API keys are random and long
+// enough for a fast unsalted hash to be sufficient
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwksFetchTimeout = 10 * time.Second
+	// jwksMinRefreshInterval limits how often a remote key set is fetched again
+	// because a token was signed with an unknown key
+	jwksMinRefreshInterval = time.Minute
+	jwksMaxSize            = 1 << 20
+)
+
+var ErrorUnknownKey = errors.New("unknown signing key")
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set, returning its signature keys by key id.
+// Keys of unsupported types are skipped
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed parsing JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecdsaKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing key \"%s\": %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (jwk *jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeKeyParameter("n", jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeKeyParameter("e", jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk *jsonWebKey) ecdsaKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve \"%s\"", jwk.Crv)
+	}
+	x, err := decodeKeyParameter("x", jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeKeyParameter("y", jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeKeyParameter(name string, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing parameter \"%s\"", name)
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter \"%s\": %w", name, err)
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+// KeySet holds the keys of a JWKS loaded from a file or an http(s) URL
+type KeySet struct {
+	source      string
+	client      *http.Client
+	lock        sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+}
+
+func LoadKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{source: source, client: &http.Client{Timeout: jwksFetchTimeout}}
+	if err := ks.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) remote() bool {
+	return strings.HasPrefix(ks.source, "http://") || strings.HasPrefix(ks.source, "https://")
+}
+
+// Reload loads the keys from the source again. The previous keys are kept if it fails
+func (ks *KeySet) Reload(ctx context.Context) error {
+	ks.lock.Lock()
+	ks.lastRefresh = time.Now()
+	ks.lock.Unlock()
+
+	keys, err := ks.load(ctx)
+	if err != nil {
+		return err
+	}
+	ks.lock.Lock()
+	ks.keys = keys
+	ks.lock.Unlock()
+	return nil
+}
+
+// load reads and parses the keys from the source. It doesn't hold the lock, so requests aren't
+// blocked while a remote key set is fetched
+func (ks *KeySet) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var data []byte
+	var err error
+	if ks.remote() {
+		data, err = ks.fetch(ctx)
+	} else {
+		data, err = os.ReadFile(ks.source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed loading JWKS from %s: %w", ks.source, err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading JWKS from %s: %w", ks.source, err)
+	}
+	return keys, nil
+}
+
+func (ks *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, jwksMaxSize))
+}
+
+// Key returns the key with the given id. A remote key set is fetched again if the
+// key is unknown, since the identity provider may have rotated its keys. Only one
+// request per refresh interval fetches it, others fail with ErrorUnknownKey meanwhile
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.lock.RLock()
+	key, ok := ks.keys[kid]
+	ks.lock.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !ks.remote() {
+		return nil, fmt.Errorf("%w \"%s\"", ErrorUnknownKey, kid)
+	}
+
+	ks.lock.Lock()
+	key, ok = ks.keys[kid]
+	refresh := !ok && time.Since(ks.lastRefresh) >= jwksMinRefreshInterval
+	if refresh {
+		ks.lastRefresh = time.Now()
+	}
+	ks.lock.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("%w \"%s\"", ErrorUnknownKey, kid)
+	}
+
+	// The fetch isn't cancelled with the request, so a client can't abort the refresh it started.
+	// The client's timeout still limits it
+	keys, err := ks.load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	ks.lock.Lock()
+	ks.keys = keys
+	ks.lock.Unlock()
+	if key, ok = keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w \"%s\"", ErrorUnknownKey, kid)
+}
@@ -0,0 +1,36 @@
+package auth
+
+import "context"
+
+// Principal is an authenticated API client
+type Principal struct {
+	Name string
+	Role Role
+	// Namespaces the client is restricted to, nil if it can access all namespaces
+	Namespaces []string
+}
+
+// AllowsNamespace reports whether the client can access jobs in the namespace
+func (p *Principal) AllowsNamespace(namespace string) bool {
+	if p.Namespaces == nil {
+		return true
+	}
+	for _, allowed := range p.Namespaces {
+		if allowed == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated client of a request, or nil if there is none
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
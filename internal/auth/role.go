@@ -0,0 +1,35 @@
+package auth
+
+import "fmt"
+
+// Role determines which operations an API client may perform. Each role
+// is allowed everything the roles below it are allowed
+type Role string
+
+const (
+	// Viewer can read jobs and their runs
+	Viewer Role = "viewer"
+	// Operator can also control the execution of jobs
+	Operator Role = "operator"
+	// Admin can also create, update and delete jobs and manage API keys
+	Admin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	Viewer:   1,
+	Operator: 2,
+	Admin:    3,
+}
+
+func ParseRole(role string) (Role, error) {
+	if _, ok := roleRanks[Role(role)]; !ok {
+		return "", fmt.Errorf("unknown role \"%s\", expected one of viewer, operator or admin", role)
+	}
+	return Role(role), nil
+}
+
+// Allows reports whether the role grants the permissions of required
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
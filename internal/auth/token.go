@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
+)
+
+// TokenConfig describes which bearer tokens are accepted and how they map to roles
+type TokenConfig struct {
+	Issuer   string
+	Audience string
+	// RoleClaim is the claim holding the client's roles, either a string or an array of
+	// strings. Nested claims are separated by dots, e.g. "realm_access.roles"
+	RoleClaim string
+	// RoleMapping maps role claim values to roles. If it is empty, claim values are
+	// used as role names directly
+	RoleMapping map[string]Role
+	// NamespaceClaim is the claim holding the namespaces the client is restricted to, in the
+	// same format as RoleClaim. If it is empty, clients can access all namespaces
+	NamespaceClaim string
+}
+
+// TokenVerifier authenticates clients by JWT bearer tokens signed with RS256 or ES256
+type TokenVerifier struct {
+	keys   *KeySet
+	config TokenConfig
+	parser *jwt.Parser
+}
+
+func NewTokenVerifier(keys *KeySet, config TokenConfig) *TokenVerifier {
+	return &TokenVerifier{
+		keys:   keys,
+		config: config,
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "ES256"})),
+	}
+}
+
+// ParseRoleMapping parses role claim mappings in the form value=role
+func ParseRoleMapping(mappings []string) (map[string]Role, error) {
+	roleMapping := make(map[string]Role, len(mappings))
+	for _, mapping := range mappings {
+		value, roleName, found := strings.Cut(mapping, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("role mapping \"%s\" is not in the form value=role", mapping)
+		}
+		role, err := ParseRole(roleName)
+		if err != nil {
+			return nil, err
+		}
+		roleMapping[value] = role
+	}
+	return roleMapping, nil
+}
+
+// Verify checks the token's signature, issuer, audience and expiry and returns the client it identifies
+func (tv *TokenVerifier) Verify(ctx context.Context, tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := tv.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return tv.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token has no expiry or is expired")
+	}
+	if !claims.VerifyIssuer(tv.config.Issuer, true) {
+		return nil, errors.New("unexpected token issuer")
+	}
+	if !claims.VerifyAudience(tv.config.Audience, true) {
+		return nil, errors.New("unexpected token audience")
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	role, err := tv.role(claims)
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{Name: subject, Role: role}
+	if tv.config.NamespaceClaim != "" {
+		principal.Namespaces = claimValues(claims, tv.config.NamespaceClaim)
+		if len(principal.Namespaces) == 0 {
+			return nil, fmt.Errorf("token has no namespaces in claim \"%s\"", tv.config.NamespaceClaim)
+		}
+	}
+	return principal, nil
+}
+
+// claimValues returns the string values of a possibly nested claim, which is either a string
+// or an array of strings
+func claimValues(claims jwt.MapClaims, path string) []string {
+	var claim interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := claim.(map[string]interface{})
+		if !ok {
+			claim = nil
+			break
+		}
+		claim = object[name]
+	}
+
+	var values []string
+	switch claim := claim.(type) {
+	case string:
+		values = []string{claim}
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// role returns the highest role granted by the token's role claim
+func (tv *TokenVerifier) role(claims jwt.MapClaims) (Role, error) {
+	var granted Role
+	for _, value := range claimValues(claims, tv.config.RoleClaim) {
+		role := Role(value)
+		if len(tv.config.RoleMapping) != 0 {
+			role = tv.config.RoleMapping[value]
+		}
+		if _, ok := roleRanks[role]; ok && !granted.Allows(role) {
+			granted = role
+		}
+	}
+	if granted == "" {
+		return "", fmt.Errorf("token grants no role in claim \"%s\"", tv.config.RoleClaim)
+	}
+	return granted, nil
+}
@@ -0,0 +1,127 @@
+package execution
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// UserAllowed reports whether jobs may run as the given user. Only users
+// listed verbatim in the allowlist are permitted, so root has to be allowed explicitly
+func (c *Config) UserAllowed(name string) bool {
+	return contains(c.AllowedUsers, name)
+}
+
+func (c *Config) GroupAllowed(name string) bool {
+	return contains(c.AllowedGroups, name)
+}
+
+// supplementaryGroupAllowed reports whether a job may keep a supplementary group of the user it runs as. The
+// group has to be allowed by name or gid, so privileged groups of an allowed user aren't passed on
+func (c *Config) supplementaryGroupAllowed(groupId string) bool {
+	if c.GroupAllowed(groupId) {
+		return true
+	}
+	g, err := user.LookupGroupId(groupId)
+	return err == nil && c.GroupAllowed(g.Name)
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return user.LookupId(name)
+	}
+	return user.Lookup(name)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return user.LookupGroupId(name)
+	}
+	return user.LookupGroup(name)
+}
+
+func parseId(id string) (uint32, error) {
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	return uint32(parsed), err
+}
+
+// ResolveUser checks that jobs may run as the user and that the user exists
+func (c *Config) ResolveUser(name string) (*user.User, error) {
+	if !c.UserAllowed(name) {
+		return nil, fmt.Errorf("running jobs as user \"%s\" is not allowed", name)
+	}
+	u, err := lookupUser(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up user \"%s\": %w", name, err)
+	}
+	return u, nil
+}
+
+// ResolveGroup checks that jobs may run as the group and that the group exists
+func (c *Config) ResolveGroup(name string) (*user.Group, error) {
+	if !c.GroupAllowed(name) {
+		return nil, fmt.Errorf("running jobs as group \"%s\" is not allowed", name)
+	}
+	g, err := lookupGroup(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed looking up group \"%s\": %w", name, err)
+	}
+	return g, nil
+}
+
+func (c *Config) credential(userName, groupName string) (*syscall.Credential, error) {
+	if userName == "" && groupName == "" {
+		return nil, nil
+	}
+
+	credential := syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid()), Groups: []uint32{}}
+	if userName != "" {
+		u, err := c.ResolveUser(userName)
+		if err != nil {
+			return nil, err
+		}
+		if credential.Uid, err = parseId(u.Uid); err != nil {
+			return nil, fmt.Errorf("failed parsing uid of user \"%s\": %w", userName, err)
+		}
+		if credential.Gid, err = parseId(u.Gid); err != nil {
+			return nil, fmt.Errorf("failed parsing gid of user \"%s\": %w", userName, err)
+		}
+		if groupName == "" {
+			groupIds, err := u.GroupIds()
+			if err != nil {
+				return nil, fmt.Errorf("failed looking up groups of user \"%s\": %w", userName, err)
+			}
+			for _, groupId := range groupIds {
+				if !c.supplementaryGroupAllowed(groupId) {
+					continue
+				}
+				gid, err := parseId(groupId)
+				if err != nil {
+					return nil, fmt.Errorf("failed parsing group id of user \"%s\": %w", userName, err)
+				}
+				credential.Groups = append(credential.Groups, gid)
+			}
+		}
+	}
+	if groupName != "" {
+		g, err := c.ResolveGroup(groupName)
+		if err != nil {
+			return nil, err
+		}
+		if credential.Gid, err = parseId(g.Gid); err != nil {
+			return nil, fmt.Errorf("failed parsing gid of group \"%s\": %w", groupName, err)
+		}
+	}
+	return &credential, nil
+}
@@ -0,0 +1,44 @@
+package execution
+
+import (
+	"fmt"
+	"os"
+)
+
+// defaultPath is the PATH of jobs if the app was started without one
+const defaultPath = "/usr/local/bin:/usr/bin:/bin"
+
+// userVariables describe the user a job runs as. Jobs which don't switch users get the app's values
+var userVariables = []string{"HOME", "USER", "LOGNAME"}
+
+// environment builds the environment of a job running as userName. The app's environment holds secrets
+// like the database password, so jobs only get PATH, variables describing their user and the variables
+// passed on explicitly
+func (c *Config) environment(userName string) ([]string, error) {
+	path, ok := os.LookupEnv("PATH")
+	if !ok {
+		path = defaultPath
+	}
+	environment := []string{"PATH=" + path}
+
+	if userName == "" {
+		for _, name := range userVariables {
+			if value, ok := os.LookupEnv(name); ok {
+				environment = append(environment, name+"="+value)
+			}
+		}
+	} else {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return nil, fmt.Errorf("failed looking up user \"%s\": %w", userName, err)
+		}
+		environment = append(environment, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	}
+
+	for _, name := range c.Environment {
+		if value, ok := os.LookupEnv(name); ok {
+			environment = append(environment, name+"="+value)
+		}
+	}
+	return environment, nil
+}
@@ -0,0 +1,127 @@
+package execution
+
+import (
+	"context"
+	"go-work/internal/model"
+	"go-work/internal/rlimit"
+	"go-work/internal/shell"
+	"go-work/internal/tracing"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config holds the server-side settings which control how jobs are executed
+type Config struct {
+	Interpreters  shell.Interpreters
+	AllowedUsers  []string
+	AllowedGroups []string
+	DefaultLimits model.Limits
+	// Environment lists the variables of the app's environment which are passed on to jobs
+	Environment []string
+
+	policyLock    sync.RWMutex
+	commandPolicy *CommandPolicy
+}
+
+// Limits returns the resource limits of job, with unset ones replaced by the defaults
+func (c *Config) Limits(job *model.Job) model.Limits {
+	limits := job.Limits
+	if limits.AddressSpace == 0 {
+		limits.AddressSpace = c.DefaultLimits.AddressSpace
+	}
+	if limits.CPUSeconds == 0 {
+		limits.CPUSeconds = c.DefaultLimits.CPUSeconds
+	}
+	if limits.OpenFiles == 0 {
+		limits.OpenFiles = c.DefaultLimits.OpenFiles
+	}
+	if limits.Processes == 0 {
+		limits.Processes = c.DefaultLimits.Processes
+	}
+	return limits
+}
+
+// Command builds the command which executes job
+func (c *Config) Command(ctx context.Context, job *model.Job) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if job.Mode == model.ShellMode {
+		executable, err := c.CheckInterpreter(job.Interpreter)
+		if err != nil {
+			return nil, err
+		}
+		cmd = c.Interpreters[job.Interpreter].Command(ctx, job.Script, job.Name, job.Arguments)
+		// Without a policy, interpreters which aren't absolute paths are left to be found in $PATH
+		if filepath.IsAbs(executable) {
+			cmd.Path = executable
+		}
+	} else {
+		executable, err := c.CheckCommand(job.Command)
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.CommandContext(ctx, executable, job.Arguments...)
+		// The process keeps the command as its name, even if it was resolved through symlinks
+		cmd.Args[0] = job.Command
+	}
+
+	credential, err := c.credential(job.RunAsUser, job.RunAsGroup)
+	if err != nil {
+		return nil, err
+	}
+	environment, err := c.environment(job.RunAsUser)
+	if err != nil {
+		return nil, err
+	}
+	// Jobs receive the trace context of their run, so they can report spans of their own
+	cmd.Env = tracing.Environment(ctx, environment)
+	if credential != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	}
+	if err = rlimit.Wrap(cmd, c.Limits(job)); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// ResourceUsage returns the resource usage of a finished process
+func ResourceUsage(state *os.ProcessState) *model.ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	usage := model.ResourceUsage{
+		UserCPUSeconds:   state.UserTime().Seconds(),
+		SystemCPUSeconds: state.SystemTime().Seconds(),
+	}
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// Linux reports the maximum resident set size in kilobytes
+		usage.MaxRSSBytes = rusage.Maxrss * 1024
+	}
+	return &usage
+}
+
+// TerminationCause reports which resource limit, if any, caused the process to terminate. Only
+// the CPU limit terminates processes, the other limits make allocations, opening files or
+// forking fail, which processes handle themselves
+func TerminationCause(state *os.ProcessState, limits model.Limits) model.TerminationCause {
+	if state == nil {
+		return ""
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return model.TerminatedByCPULimit
+	case syscall.SIGKILL:
+		cpuTime := state.UserTime() + state.SystemTime()
+		if limits.CPUSeconds != 0 && cpuTime >= time.Duration(limits.CPUSeconds)*time.Second {
+			return model.TerminatedByCPULimit
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package execution
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// OutputTailSize is the number of bytes of a run's output which are kept
+const OutputTailSize = 4096
+
+// TailBuffer keeps the last bytes written to it. It is safe for concurrent use, so it can collect both
+// stdout and stderr of a process
+type TailBuffer struct {
+	lock      sync.Mutex
+	size      int
+	data      []byte
+	truncated bool
+}
+
+func NewTailBuffer(size int) *TailBuffer {
+	return &TailBuffer{size: size, data: make([]byte, 0, size)}
+}
+
+// Write never fails, output beyond the buffer's size pushes out the oldest bytes
+func (tb *TailBuffer) Write(p []byte) (int, error) {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	written := len(p)
+	if len(p) >= tb.size {
+		tb.truncated = tb.truncated || len(tb.data) > 0 || len(p) > tb.size
+		tb.data = append(tb.data[:0], p[len(p)-tb.size:]...)
+		return written, nil
+	}
+	if overflow := len(tb.data) + len(p) - tb.size; overflow > 0 {
+		tb.truncated = true
+		tb.data = append(tb.data[:0], tb.data[overflow:]...)
+	}
+	tb.data = append(tb.data, p...)
+	return written, nil
+}
+
+// String returns the kept output. If earlier output was dropped, the partial first line is removed, along
+// with any bytes which aren't valid UTF-8, since the output is stored as text
+func (tb *TailBuffer) String() string {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	tail := string(tb.data)
+	if tb.truncated {
+		if newline := strings.IndexByte(tail, '\n'); newline >= 0 && newline < len(tail)-1 {
+			tail = tail[newline+1:]
+		}
+	}
+	if !utf8.ValidString(tail) {
+		tail = strings.ToValidUTF8(tail, "")
+	}
+	// PostgreSQL text can't hold NUL bytes
+	return strings.ReplaceAll(tail, "\x00", "")
+}
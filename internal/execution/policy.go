@@ -0,0 +1,140 @@
+package execution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CommandPolicy restricts the executables jobs are allowed to run. Commands must be referred to
+// by clean absolute paths, and are matched by the path they resolve to after following symlinks,
+// so a symlink in an allowed directory can't point outside of it
+type CommandPolicy struct {
+	AllowedPaths       []string `json:"allowedPaths"`
+	AllowedDirectories []string `json:"allowedDirectories"`
+}
+
+func isCleanAbsolute(path string) bool {
+	return filepath.IsAbs(path) && filepath.Clean(path) == path
+}
+
+// LoadCommandPolicy reads a command policy from a JSON file
+func LoadCommandPolicy(path string) (*CommandPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening command policy file: %w", err)
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+	policy := CommandPolicy{}
+	if err = dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed decoding command policy file %s: %w", path, err)
+	}
+	for _, entries := range [][]string{policy.AllowedPaths, policy.AllowedDirectories} {
+		for i, allowed := range entries {
+			if !isCleanAbsolute(allowed) {
+				return nil, fmt.Errorf("command policy entry \"%s\" is not a clean absolute path", allowed)
+			}
+			entries[i] = resolveAllowed(allowed)
+		}
+	}
+	return &policy, nil
+}
+
+// resolveAllowed follows the symlinks of a policy entry, so entries like /bin/sh still match when /bin
+// is a symlink. Entries which don't exist yet are kept as they are
+func resolveAllowed(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+// Check returns the path command resolves to after following symlinks, or an error if the policy
+// doesn't allow the resolved path. The resolved path should be executed, so the command can't be
+// replaced by a symlink after it was checked
+func (p *CommandPolicy) Check(command string) (string, error) {
+	if !isCleanAbsolute(command) {
+		return "", fmt.Errorf("command \"%s\" must be a clean absolute path", command)
+	}
+	resolved, err := filepath.EvalSymlinks(command)
+	if err != nil {
+		return "", fmt.Errorf("failed resolving command \"%s\": %w", command, err)
+	}
+	for _, path := range p.AllowedPaths {
+		if resolved == path {
+			return resolved, nil
+		}
+	}
+	for _, directory := range p.AllowedDirectories {
+		if strings.HasPrefix(resolved, strings.TrimSuffix(directory, "/")+"/") {
+			return resolved, nil
+		}
+	}
+	if resolved != command {
+		return "", fmt.Errorf(
+			"command \"%s\" resolves to \"%s\", which is not allowed by the command policy",
+			command,
+			resolved,
+		)
+	}
+	return "", fmt.Errorf("command \"%s\" is not allowed by the command policy", command)
+}
+
+// SetCommandPolicy replaces the command policy. A nil policy allows any command
+func (c *Config) SetCommandPolicy(policy *CommandPolicy) {
+	c.policyLock.Lock()
+	defer c.policyLock.Unlock()
+	c.commandPolicy = policy
+}
+
+// ReloadCommandPolicy replaces the command policy with the one in the file at path
+func (c *Config) ReloadCommandPolicy(path string) error {
+	policy, err := LoadCommandPolicy(path)
+	if err != nil {
+		return err
+	}
+	c.SetCommandPolicy(policy)
+	return nil
+}
+
+// CheckCommand returns the executable to run for command, or an error if the command policy doesn't
+// allow running it. With a policy, the executable is the path command resolves to
+func (c *Config) CheckCommand(command string) (string, error) {
+	c.policyLock.RLock()
+	defer c.policyLock.RUnlock()
+	if c.commandPolicy == nil {
+		return command, nil
+	}
+	return c.commandPolicy.Check(command)
+}
+
+// CheckInterpreter returns the executable to run for the interpreter, or an error if the interpreter
+// isn't configured or the command policy doesn't allow running its executable. With a policy,
+// interpreters found through $PATH are resolved like commands
+func (c *Config) CheckInterpreter(name string) (string, error) {
+	interpreter, ok := c.Interpreters[name]
+	if !ok {
+		return "", fmt.Errorf("interpreter \"%s\" is not configured", name)
+	}
+
+	c.policyLock.RLock()
+	defer c.policyLock.RUnlock()
+	if c.commandPolicy == nil {
+		return interpreter.Executable, nil
+	}
+	path, err := exec.LookPath(interpreter.Executable)
+	if err == nil {
+		path, err = filepath.Abs(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed finding executable of interpreter \"%s\": %w", name, err)
+	}
+	return c.commandPolicy.Check(path)
+}
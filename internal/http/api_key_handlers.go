@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/model"
+	"net/http"
+)
+
+var (
+	listAPIKeysErrorHandler  = herrors.NewErrorHandler("ListAPIKeys")
+	createAPIKeyErrorHandler = herrors.NewErrorHandler("CreateAPIKey")
+	deleteAPIKeyErrorHandler = herrors.NewErrorHandler("DeleteAPIKey")
+)
+
+type requestAPIKey struct {
+	Name string    `json:"name" validate:"required,max=255"`
+	Role auth.Role `json:"role" validate:"required,oneof=viewer operator admin"`
+	// Namespaces restrict the key to jobs of these namespaces, all namespaces are accessible if it is empty
+	Namespaces []string `json:"namespaces" validate:"dive,namespace"`
+}
+
+type responseAPIKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+func (js *jobServer) listAPIKeysHandler(w http.ResponseWriter, req *http.Request) {
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	keys, err := js.storage.ListAPIKeys(timeoutCtx)
+	if err != nil {
+		listAPIKeysErrorHandler.WriteAndLogError(
+			w,
+			"failed to list API keys",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, keys)
+}
+
+func (js *jobServer) createAPIKeyHandler(w http.ResponseWriter, req *http.Request) {
+	rk := requestAPIKey{}
+	if !decodeJSONBody(w, req, &rk, createAPIKeyErrorHandler) {
+		return
+	}
+	if err := js.validate.Struct(rk); err != nil {
+		createAPIKeyErrorHandler.WriteAndLogValidationErrors(
+			w,
+			err.(validator.ValidationErrors),
+			log.Fields{"request_api_key": rk},
+		)
+		return
+	}
+
+	key, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		createAPIKeyErrorHandler.WriteAndLogError(
+			w,
+			"failed to generate API key",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	apiKey, err := js.storage.CreateAPIKey(timeoutCtx, rk.Name, rk.Role, rk.Namespaces, keyHash)
+	if err != nil {
+		statusCode := http.StatusConflict
+		if !errors.Is(err, model.ErrorAPIKeyExists) {
+			statusCode = http.StatusInternalServerError
+		}
+		createAPIKeyErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to create API key %s", rk.Name),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, responseAPIKey{apiKey, key})
+}
+
+func (js *jobServer) deleteAPIKeyHandler(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	err := js.storage.DeleteAPIKey(timeoutCtx, name)
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorAPIKeyNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
+		deleteAPIKeyErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to delete API key %s", name),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
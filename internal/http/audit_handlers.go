@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/logging"
+	"go-work/internal/model"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+var getAuditEntriesErrorHandler = herrors.NewErrorHandler("GetAuditEntries")
+
+func (js *jobServer) getAuditEntriesHandler(w http.ResponseWriter, req *http.Request) {
+	filter := model.AuditFilter{Actor: req.URL.Query().Get("actor")}
+	if jobIdParam := req.URL.Query().Get("jobId"); jobIdParam != "" {
+		jobId, err := strconv.ParseInt(jobIdParam, 10, 64)
+		if err == nil && jobId <= 0 {
+			err = errors.New("job id out of range")
+		}
+		if err != nil {
+			getAuditEntriesErrorHandler.WriteAndLogError(
+				w,
+				"jobId must be a positive integer",
+				err,
+				http.StatusBadRequest,
+				log.Fields{logging.JobIdField: jobIdParam},
+			)
+			return
+		}
+		filter.JobId = model.JobId(jobId)
+	}
+	var ok bool
+	if filter.Limit, ok = parseLimit(w, req, defaultAuditLimit, maxAuditLimit, getAuditEntriesErrorHandler); !ok {
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	entries, err := js.storage.GetAuditEntries(timeoutCtx, filter)
+	if err != nil {
+		getAuditEntriesErrorHandler.WriteAndLogError(
+			w,
+			"failed to get audit entries",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, entries)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/model"
+	"net/http"
+	"strings"
+)
+
+const (
+	apiKeyHeader        = "X-API-Key"
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+var authenticationErrorHandler = herrors.NewErrorHandler("Authentication")
+
+func (js *jobServer) authenticationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get(authorizationHeader); authorization != "" {
+			js.authenticateToken(w, r, authorization, next)
+			return
+		}
+
+		key := r.Header.Get(apiKeyHeader)
+		if key == "" {
+			authenticationErrorHandler.WriteAndLogError(
+				w,
+				"missing API key or bearer token",
+				errors.New("no "+apiKeyHeader+" or "+authorizationHeader+" header"),
+				http.StatusUnauthorized,
+				log.Fields{"remote_address": r.RemoteAddr},
+			)
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(r.Context(), constants.StorageOperationTimeout)
+		defer cancel()
+		apiKey, err := js.storage.AuthenticateAPIKey(timeoutCtx, auth.HashAPIKey(key))
+		if err != nil {
+			message, statusCode := "invalid API key", http.StatusUnauthorized
+			if !errors.Is(err, model.ErrorAPIKeyNotFound) {
+				message, statusCode = "failed to authenticate API key", http.StatusInternalServerError
+			}
+			authenticationErrorHandler.WriteAndLogError(
+				w,
+				message,
+				err,
+				statusCode,
+				log.Fields{"remote_address": r.RemoteAddr},
+			)
+			return
+		}
+		// Failing to record the use doesn't affect the client, whose key is valid
+		if err = js.storage.RecordAPIKeyUse(timeoutCtx, apiKey); err != nil {
+			logger.WithContext(r.Context()).WithField("principal", apiKey.Name).Warnf("Could not record use of API key: %s", err)
+		}
+
+		principal := &auth.Principal{Name: apiKey.Name, Role: apiKey.Role}
+		if len(apiKey.Namespaces) != 0 {
+			principal.Namespaces = apiKey.Namespaces
+		}
+		ctx := auth.ContextWithPrincipal(r.Context(), principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (js *jobServer) authenticateToken(w http.ResponseWriter, r *http.Request, authorization string, next http.Handler) {
+	if js.tokenVerifier == nil {
+		authenticationErrorHandler.WriteAndLogError(
+			w,
+			"bearer tokens are not accepted",
+			errors.New("bearer token authentication is not configured"),
+			http.StatusUnauthorized,
+			log.Fields{"remote_address": r.RemoteAddr},
+		)
+		return
+	}
+	if len(authorization) < len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		authenticationErrorHandler.WriteAndLogError(
+			w,
+			"expect a bearer token",
+			errors.New("unsupported "+authorizationHeader+" scheme"),
+			http.StatusUnauthorized,
+			log.Fields{"remote_address": r.RemoteAddr},
+		)
+		return
+	}
+
+	principal, err := js.tokenVerifier.Verify(r.Context(), strings.TrimSpace(authorization[len(bearerPrefix):]))
+	if err != nil {
+		authenticationErrorHandler.WriteAndLogError(
+			w,
+			"invalid bearer token",
+			err,
+			http.StatusUnauthorized,
+			log.Fields{"remote_address": r.RemoteAddr},
+		)
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+}
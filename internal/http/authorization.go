@@ -0,0 +1,158 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	herrors "go-work/internal/http/errors"
+	"net/http"
+)
+
+const (
+	createJobRoute    = "CreateJob"
+	getJobRoute       = "GetJob"
+	deleteJobRoute    = "DeleteJob"
+	restoreJobRoute   = "RestoreJob"
+	triggerJobRoute   = "TriggerJob"
+	updateJobRoute    = "UpdateJob"
+	getJobByNameRoute = "GetJobByName"
+	getRunsRoute      = "GetRuns"
+	getRunRoute       = "GetRun"
+	cancelRunRoute    = "CancelRun"
+	getJobStatsRoute  = "GetJobStats"
+	listJobsRoute     = "ListJobs"
+	pauseJobsRoute    = "PauseJobs"
+	resumeJobsRoute   = "ResumeJobs"
+	deleteJobsRoute   = "DeleteJobs"
+	getQuotaRoute     = "GetQuota"
+	setQuotaRoute     = "SetQuota"
+
+	listSLAViolationsRoute = "ListSLAViolations"
+
+	getRevisionsRoute  = "GetRevisions"
+	getRevisionRoute   = "GetRevision"
+	diffRevisionsRoute = "DiffRevisions"
+	rollbackJobRoute   = "RollbackJob"
+
+	createNotificationRuleRoute    = "CreateNotificationRule"
+	listNotificationRulesRoute     = "ListNotificationRules"
+	getNotificationRuleRoute       = "GetNotificationRule"
+	deleteNotificationRuleRoute    = "DeleteNotificationRule"
+	getNotificationDeliveriesRoute = "GetNotificationDeliveries"
+	testNotificationRuleRoute      = "TestNotificationRule"
+
+	listAPIKeysRoute  = "ListAPIKeys"
+	createAPIKeyRoute = "CreateAPIKey"
+	deleteAPIKeyRoute = "DeleteAPIKey"
+
+	getAuditEntriesRoute = "GetAuditEntries"
+	getStatusRoute       = "GetStatus"
+	listSchedulersRoute  = "ListSchedulers"
+)
+
+// routePermissions holds the role required for each route. Routes missing from it can't be accessed
+var routePermissions = map[string]auth.Role{
+	createJobRoute:    auth.Admin,
+	getJobRoute:       auth.Viewer,
+	deleteJobRoute:    auth.Admin,
+	restoreJobRoute:   auth.Admin,
+	triggerJobRoute:   auth.Operator,
+	updateJobRoute:    auth.Admin,
+	getJobByNameRoute: auth.Viewer,
+	getRunsRoute:      auth.Viewer,
+	getRunRoute:       auth.Viewer,
+	cancelRunRoute:    auth.Operator,
+	getJobStatsRoute:  auth.Viewer,
+	listJobsRoute:     auth.Viewer,
+	pauseJobsRoute:    auth.Operator,
+	resumeJobsRoute:   auth.Operator,
+	deleteJobsRoute:   auth.Admin,
+	getQuotaRoute:     auth.Viewer,
+	setQuotaRoute:     auth.Admin,
+
+	listSLAViolationsRoute: auth.Viewer,
+
+	getRevisionsRoute:  auth.Viewer,
+	getRevisionRoute:   auth.Viewer,
+	diffRevisionsRoute: auth.Viewer,
+	rollbackJobRoute:   auth.Admin,
+
+	createNotificationRuleRoute:    auth.Admin,
+	listNotificationRulesRoute:     auth.Viewer,
+	getNotificationRuleRoute:       auth.Viewer,
+	deleteNotificationRuleRoute:    auth.Admin,
+	getNotificationDeliveriesRoute: auth.Viewer,
+	testNotificationRuleRoute:      auth.Operator,
+
+	listAPIKeysRoute:  auth.Admin,
+	createAPIKeyRoute: auth.Admin,
+	deleteAPIKeyRoute: auth.Admin,
+
+	getAuditEntriesRoute: auth.Admin,
+	getStatusRoute:       auth.Viewer,
+	listSchedulersRoute:  auth.Viewer,
+}
+
+// globalRoutes can only be accessed by clients which can access all namespaces, because they aren't scoped
+// to a namespace or, like setting quotas, would let clients lift their own restrictions
+var globalRoutes = map[string]bool{
+	setQuotaRoute:        true,
+	listAPIKeysRoute:     true,
+	createAPIKeyRoute:    true,
+	deleteAPIKeyRoute:    true,
+	getAuditEntriesRoute: true,
+	getStatusRoute:       true,
+	listSchedulersRoute:  true,
+}
+
+var authorizationErrorHandler = herrors.NewErrorHandler("Authorization")
+
+func authorizationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil {
+			authorizationErrorHandler.WriteAndLogError(
+				w,
+				"request is not authenticated",
+				errors.New("no principal in request context"),
+				http.StatusInternalServerError,
+				log.Fields{},
+			)
+			return
+		}
+
+		routeName := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			routeName = route.GetName()
+		}
+		required, ok := routePermissions[routeName]
+		if !ok || !principal.Role.Allows(required) {
+			authorizationErrorHandler.WriteAndLogError(
+				w,
+				"insufficient permissions",
+				fmt.Errorf("role \"%s\" is not allowed to access route \"%s\"", principal.Role, routeName),
+				http.StatusForbidden,
+				log.Fields{"principal": principal.Name},
+			)
+			return
+		}
+
+		allowed := principal.Namespaces == nil
+		if !globalRoutes[routeName] {
+			allowed = principal.AllowsNamespace(namespaceFromRequest(r))
+		}
+		if !allowed {
+			authorizationErrorHandler.WriteAndLogError(
+				w,
+				"insufficient permissions",
+				fmt.Errorf("not allowed to access namespace \"%s\"", namespaceFromRequest(r)),
+				http.StatusForbidden,
+				log.Fields{"principal": principal.Name},
+			)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
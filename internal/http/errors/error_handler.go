@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	log "github.com/sirupsen/logrus"
+	"go-work/internal/logging"
+	"go-work/internal/requestid"
 	"net/http"
 )
 
+var logger = logging.ForPackage("http")
+
 type ErrorHandler struct {
 	endpoint string
 }
@@ -27,12 +31,12 @@ func (eh *ErrorHandler) WriteAndLogError(
 	statusCode int,
 	fields log.Fields,
 ) {
-	fields["endpoint"] = eh.endpoint
+	entry := eh.entry(w, fields)
 	logErr := fmt.Sprintf("%s: %s", message, err)
 	if statusCode >= 500 {
-		log.WithFields(fields).Error(logErr)
+		entry.Error(logErr)
 	} else {
-		log.WithFields(fields).Debug(logErr)
+		entry.Debug(logErr)
 	}
 	eh.writeJsonErrorMsg(w, message, statusCode)
 }
@@ -42,17 +46,25 @@ func (eh *ErrorHandler) WriteAndLogValidationErrors(
 	err validator.ValidationErrors,
 	fields log.Fields,
 ) {
-	fields["endpoint"] = eh.endpoint
 	fieldErrors := make(map[string]string)
 	for _, fieldError := range err {
 		fieldErrors[fieldError.Field()] = fieldError.Error()
 	}
-	log.WithFields(fields).Debugf("Received json with invalid fields: %s", fieldErrors)
-	fields["field errors"] = fieldErrors
+	eh.entry(w, fields).Debugf("Received json with invalid fields: %s", fieldErrors)
 	resp, _ := json.Marshal(fieldErrors)
 	eh.writeJson(w, resp, http.StatusUnprocessableEntity)
 }
 
+// entry returns the log entry of an error. The request id is taken from the response, which it is set on before
+// requests are handled
+func (eh *ErrorHandler) entry(w http.ResponseWriter, fields log.Fields) *log.Entry {
+	fields["endpoint"] = eh.endpoint
+	if id := w.Header().Get(requestid.Header); id != "" {
+		fields[logging.RequestIdField] = id
+	}
+	return logger.WithFields(fields)
+}
+
 func (eh *ErrorHandler) writeJson(w http.ResponseWriter, jsonMsg []byte, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
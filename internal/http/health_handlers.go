@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"go-work/internal/model"
+	"go-work/internal/scheduler"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout is shorter than the storage operation timeout, because probes give up quickly
+const healthCheckTimeout = 5 * time.Second
+
+// healthServer serves the endpoints which report whether the app works
+type healthServer struct {
+	storage    model.HealthStorage
+	schedulers []*scheduler.Scheduler
+	startTime  time.Time
+}
+
+type readiness struct {
+	Ready bool `json:"ready"`
+	// Failures lists the names of the failed checks
+	Failures []string `json:"failures,omitempty"`
+}
+
+type databaseStatus struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type serverStatus struct {
+	readiness
+	StartTime  time.Time          `json:"startTime"`
+	Database   databaseStatus     `json:"database"`
+	Schedulers []scheduler.Status `json:"schedulers"`
+}
+
+// check runs all health checks
+func (hs *healthServer) check(ctx context.Context) *serverStatus {
+	status := serverStatus{
+		readiness:  readiness{Ready: true},
+		StartTime:  hs.startTime,
+		Database:   databaseStatus{Healthy: true},
+		Schedulers: []scheduler.Status{},
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := hs.storage.CheckHealth(timeoutCtx); err != nil {
+		logger.WithContext(ctx).Errorf("Health check of database failed: %s", err)
+		status.Database = databaseStatus{Healthy: false, Error: err.Error()}
+		status.Ready = false
+		status.Failures = append(status.Failures, "database")
+	}
+
+	now := time.Now()
+	for _, skd := range hs.schedulers {
+		schedulerStatus := skd.Status(now)
+		if !schedulerStatus.Healthy {
+			status.Ready = false
+			status.Failures = append(status.Failures, "scheduler "+schedulerStatus.Name)
+		}
+		status.Schedulers = append(status.Schedulers, schedulerStatus)
+	}
+	return &status
+}
+
+// writeHealth responds with 503 Service Unavailable if the app isn't ready, so probes which only
+// look at status codes notice
+func writeHealth(w http.ResponseWriter, ready bool, v interface{}) {
+	if !ready {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, v)
+}
+
+// livenessHandler only reports that the app serves requests
+func (hs *healthServer) livenessHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// readinessHandler reports whether the database is reachable with an up-to-date schema and all
+// schedulers are making progress. Details of the failures are only logged, because the endpoint
+// doesn't require authentication
+func (hs *healthServer) readinessHandler(w http.ResponseWriter, req *http.Request) {
+	status := hs.check(req.Context())
+	writeHealth(w, status.Ready, &status.readiness)
+}
+
+// statusHandler reports the same checks as readinessHandler in detail, including the last ticks of
+// the schedulers
+func (hs *healthServer) statusHandler(w http.ResponseWriter, req *http.Request) {
+	status := hs.check(req.Context())
+	writeHealth(w, status.Ready, status)
+}
@@ -8,20 +8,33 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	"go-work/internal/auth"
+	"go-work/internal/execution"
 	"go-work/internal/http/constants"
 	herrors "go-work/internal/http/errors"
 	"go-work/internal/http/validation"
+	"go-work/internal/logging"
 	"go-work/internal/model"
+	"go-work/internal/notification"
+	"go-work/internal/requestid"
+	"go-work/internal/scheduler"
+	"go-work/internal/shell"
 	"mime"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
+var logger = logging.ForPackage("http")
+
 type jobServer struct {
-	storage  model.JobStorage
-	validate *validator.Validate
+	storage       model.Storage
+	validate      *validator.Validate
+	tokenVerifier *auth.TokenVerifier
+	// notifier is nil if notifications are disabled
+	notifier *notification.Notifier
 }
 
 func writeJSON(w http.ResponseWriter, v interface{}) {
@@ -39,118 +52,253 @@ var (
 	getJobErrorHandler       = herrors.NewErrorHandler("GetJob")
 	getJobByNameErrorHandler = herrors.NewErrorHandler("GetJobByName")
 	deleteJobErrorHandler    = herrors.NewErrorHandler("DeleteJob")
+	restoreJobErrorHandler   = herrors.NewErrorHandler("RestoreJob")
+	triggerJobErrorHandler   = herrors.NewErrorHandler("TriggerJob")
+	updateJobErrorHandler    = herrors.NewErrorHandler("UpdateJob")
 )
 
 type requestJob struct {
-	Name          string   `json:"name" validate:"required,uniqueName"`
-	CrontabString string   `json:"crontabString" validate:"required,crontabString"`
-	Command       string   `json:"command" validate:"required"`
-	Arguments     []string `json:"arguments"`
-	Timeout       uint     `json:"timeout" validate:"required"`
+	Name          string            `json:"name" validate:"required,uniqueName"`
+	CrontabString string            `json:"crontabString" validate:"required,crontabString"`
+	Mode          model.JobMode     `json:"mode" validate:"oneof=command shell"`
+	Command       string            `json:"command" validate:"required_unless=Mode shell,onlyInMode=command,omitempty,allowedCommand"`
+	Script        string            `json:"script" validate:"required_if=Mode shell,onlyInMode=shell,script"`
+	Interpreter   string            `json:"interpreter" validate:"onlyInMode=shell,omitempty,interpreter"`
+	Arguments     []string          `json:"arguments"`
+	Timeout       uint              `json:"timeout" validate:"required"`
+	RunAsUser     string            `json:"runAsUser" validate:"omitempty,allowedUser"`
+	RunAsGroup    string            `json:"runAsGroup" validate:"omitempty,allowedGroup"`
+	Limits        requestLimits     `json:"limits"`
+	Labels        map[string]string `json:"labels" validate:"labels"`
+	// MaxSuccessInterval is at most a year
+	MaxSuccessInterval uint `json:"maxSuccessInterval" validate:"max=31536000"`
+}
+
+type requestLimits struct {
+	AddressSpace uint64 `json:"addressSpace" validate:"max=9223372036854775807"`
+	CPUSeconds   uint64 `json:"cpuSeconds" validate:"max=9223372036854775807"`
+	OpenFiles    uint64 `json:"openFiles" validate:"max=9223372036854775807"`
+	Processes    uint64 `json:"processes" validate:"max=9223372036854775807"`
+}
+
+func (rj *requestJob) setDefaults() {
+	if rj.Mode == "" {
+		rj.Mode = model.CommandMode
+	}
+	if rj.Mode == model.ShellMode && rj.Interpreter == "" {
+		rj.Interpreter = shell.DefaultInterpreter
+	}
+}
+
+func (rj *requestJob) toJob() *model.Job {
+	return &model.Job{
+		Name:               rj.Name,
+		CrontabString:      rj.CrontabString,
+		Mode:               rj.Mode,
+		Command:            rj.Command,
+		Script:             rj.Script,
+		Interpreter:        rj.Interpreter,
+		Arguments:          rj.Arguments,
+		Timeout:            rj.Timeout,
+		RunAsUser:          rj.RunAsUser,
+		RunAsGroup:         rj.RunAsGroup,
+		Limits:             model.Limits(rj.Limits),
+		Labels:             rj.Labels,
+		MaxSuccessInterval: rj.MaxSuccessInterval,
+	}
+}
+
+func requestJobFromJob(job *model.Job) requestJob {
+	return requestJob{
+		Name:               job.Name,
+		CrontabString:      job.CrontabString,
+		Mode:               job.Mode,
+		Command:            job.Command,
+		Script:             job.Script,
+		Interpreter:        job.Interpreter,
+		Arguments:          job.Arguments,
+		Timeout:            job.Timeout,
+		RunAsUser:          job.RunAsUser,
+		RunAsGroup:         job.RunAsGroup,
+		Limits:             requestLimits(job.Limits),
+		Labels:             job.Labels,
+		MaxSuccessInterval: job.MaxSuccessInterval,
+	}
 }
 
 type responseId struct {
 	Id model.JobId `json:"id"`
 }
 
-func (js *jobServer) createJobHandler(w http.ResponseWriter, req *http.Request) {
-
+// decodeJSONBody decodes a JSON request body into v, writing an error response if it fails
+func decodeJSONBody(w http.ResponseWriter, req *http.Request, v any, errorHandler *herrors.ErrorHandler) bool {
 	contentType := req.Header.Get("Content-Type")
 	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		createJobErrorHandler.WriteAndLogError(
+		errorHandler.WriteAndLogError(
 			w,
 			"failed to parse media type",
 			err, http.StatusBadRequest,
 			log.Fields{"header": contentType},
 		)
-		return
+		return false
 	}
 	if mediaType != "application/json" {
-		createJobErrorHandler.WriteAndLogError(
+		errorHandler.WriteAndLogError(
 			w,
 			"expect application/json Content-Type",
 			errors.New("Content-Type error"),
 			http.StatusUnsupportedMediaType,
-			log.Fields{"media type": mediaType},
+			log.Fields{"media_type": mediaType},
 		)
-		return
+		return false
 	}
 	dec := json.NewDecoder(req.Body)
 	dec.DisallowUnknownFields()
-	rj := requestJob{}
-	if err = dec.Decode(&rj); err != nil {
-		createJobErrorHandler.WriteAndLogError(
+	if err = dec.Decode(v); err != nil {
+		errorHandler.WriteAndLogError(
 			w,
 			"failed to parse request body",
 			err,
 			http.StatusBadRequest,
 			log.Fields{},
 		)
+		return false
+	}
+	return true
+}
+
+func (js *jobServer) createJobHandler(w http.ResponseWriter, req *http.Request) {
+	rj := requestJob{}
+	if !decodeJSONBody(w, req, &rj, createJobErrorHandler) {
 		return
 	}
 
-	background := context.Background()
-	err = js.validate.StructCtx(background, rj)
+	rj.setDefaults()
+	namespace := namespaceFromRequest(req)
+	ctx := req.Context()
+	err := js.validate.StructCtx(validation.ContextWithNamespace(ctx, namespace), rj)
 	if err != nil {
 		createJobErrorHandler.WriteAndLogValidationErrors(
 			w,
 			err.(validator.ValidationErrors),
-			log.Fields{"request job": rj},
+			log.Fields{logging.NamespaceField: namespace, logging.JobNameField: rj.Name},
 		)
 		return
 	}
 
-	timeoutCtx, cancel := context.WithTimeout(background, constants.StorageOperationTimeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, constants.StorageOperationTimeout)
 	defer cancel()
-	id, err := js.storage.CreateJob(
-		timeoutCtx,
-		rj.Name,
-		rj.CrontabString,
-		rj.Command,
-		rj.Arguments,
-		rj.Timeout,
-	)
+	job := rj.toJob()
+	job.Namespace = namespace
+	id, err := js.storage.CreateJob(timeoutCtx, job)
 	if err != nil {
+		message, statusCode := "failed to save new job", http.StatusInternalServerError
+		if errors.Is(err, model.ErrorQuotaExceeded) {
+			message, statusCode = "the quota of the namespace doesn't allow this job", http.StatusForbidden
+		}
 		createJobErrorHandler.WriteAndLogError(
 			w,
-			"failed to save new job",
+			message,
 			err,
-			http.StatusInternalServerError,
-			log.Fields{"request job": rj},
+			statusCode,
+			log.Fields{logging.NamespaceField: namespace, logging.JobNameField: rj.Name},
 		)
 		return
 	}
 	writeJSON(w, responseId{id})
 }
 
+func (js *jobServer) updateJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	rj := requestJob{}
+	if !decodeJSONBody(w, req, &rj, updateJobErrorHandler) {
+		return
+	}
+
+	rj.setDefaults()
+	namespace := namespaceFromRequest(req)
+	ctx := req.Context()
+	validationCtx := validation.ContextWithUpdatedJob(validation.ContextWithNamespace(ctx, namespace), model.JobId(id))
+	err := js.validate.StructCtx(validationCtx, rj)
+	if err != nil {
+		updateJobErrorHandler.WriteAndLogValidationErrors(
+			w,
+			err.(validator.ValidationErrors),
+			log.Fields{logging.NamespaceField: namespace, logging.JobIdField: id, logging.JobNameField: rj.Name},
+		)
+		return
+	}
+
+	job := rj.toJob()
+	job.Id = model.JobId(id)
+	timeoutCtx, cancel := context.WithTimeout(ctx, constants.StorageOperationTimeout)
+	defer cancel()
+	updated, err := js.storage.UpdateJob(timeoutCtx, job)
+	if err != nil {
+		writeChangeDefinitionError(w, model.JobId(id), err, updateJobErrorHandler)
+		return
+	}
+	writeJSON(w, updated)
+}
+
+// writeChangeDefinitionError writes the error response of a failed update or rollback of a job
+func writeChangeDefinitionError(w http.ResponseWriter, id model.JobId, err error, errorHandler *herrors.ErrorHandler) {
+	message, statusCode := fmt.Sprintf("failed to change job with id %d", id), http.StatusInternalServerError
+	if errors.Is(err, model.ErrorNotFound) {
+		message, statusCode = fmt.Sprintf("failed to get job by id %d", id), http.StatusNotFound
+	} else if errors.Is(err, model.ErrorRevisionNotFound) {
+		message, statusCode = fmt.Sprintf("failed to get revision of job with id %d", id), http.StatusNotFound
+	} else if errors.Is(err, model.ErrorJobExists) {
+		message, statusCode = "a job with the same name already exists", http.StatusConflict
+	} else if errors.Is(err, model.ErrorQuotaExceeded) {
+		message, statusCode = "the quota of the namespace doesn't allow this job", http.StatusForbidden
+	}
+	errorHandler.WriteAndLogError(w, message, err, statusCode, log.Fields{})
+}
+
 func (js *jobServer) getJobHandler(w http.ResponseWriter, req *http.Request) {
 	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), constants.StorageOperationTimeout)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
 	defer cancel()
-	job, err := js.storage.GetJob(timeoutCtx, model.JobId(id))
+	job, ok := js.findJob(timeoutCtx, w, model.JobId(id), getJobErrorHandler)
+	if !ok {
+		return
+	}
+	writeJSON(w, job)
+}
+
+// findJob gets the job by id, writing an error response if it can't be found
+func (js *jobServer) findJob(
+	ctx context.Context,
+	w http.ResponseWriter,
+	id model.JobId,
+	errorHandler *herrors.ErrorHandler,
+) (*model.Job, bool) {
+	job, err := js.storage.GetJob(ctx, id)
 	if err != nil {
 		statusCode := http.StatusNotFound
 		if !errors.Is(err, model.ErrorNotFound) {
 			statusCode = http.StatusInternalServerError
 		}
-		getJobErrorHandler.WriteAndLogError(
+		errorHandler.WriteAndLogError(
 			w,
 			fmt.Sprintf("failed to get job by id %d", id),
 			err,
 			statusCode,
 			log.Fields{},
 		)
-		return
+		return nil, false
 	}
-	writeJSON(w, job)
+	return job, true
 }
 
 func (js *jobServer) getJobByNameHandler(w http.ResponseWriter, req *http.Request) {
 	name := mux.Vars(req)["name"]
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), constants.StorageOperationTimeout)
+	namespace := namespaceFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
 	defer cancel()
-	job, err := js.storage.GetJobByName(timeoutCtx, name)
+	job, err := js.storage.GetJobByName(timeoutCtx, namespace, name)
 	if err != nil {
 		statusCode := http.StatusNotFound
 		if !errors.Is(err, model.ErrorNotFound) {
@@ -161,7 +309,7 @@ func (js *jobServer) getJobByNameHandler(w http.ResponseWriter, req *http.Reques
 			fmt.Sprintf("failed to get job by name %s", name),
 			err,
 			statusCode,
-			log.Fields{},
+			log.Fields{logging.NamespaceField: namespace},
 		)
 		return
 	}
@@ -170,15 +318,19 @@ func (js *jobServer) getJobByNameHandler(w http.ResponseWriter, req *http.Reques
 
 func (js *jobServer) deleteJobHandler(w http.ResponseWriter, req *http.Request) {
 	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), constants.StorageOperationTimeout)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
 	defer cancel()
 	err := js.storage.DeleteJob(timeoutCtx, model.JobId(id))
 	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
 		deleteJobErrorHandler.WriteAndLogError(
 			w,
 			fmt.Sprintf("failed to delete job with id %d", id),
 			err,
-			http.StatusInternalServerError,
+			statusCode,
 			log.Fields{},
 		)
 		return
@@ -186,16 +338,94 @@ func (js *jobServer) deleteJobHandler(w http.ResponseWriter, req *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+func (js *jobServer) restoreJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	job, err := js.storage.RestoreJob(timeoutCtx, model.JobId(id))
+	if err != nil {
+		message, statusCode := fmt.Sprintf("failed to restore job with id %d", id), http.StatusInternalServerError
+		if errors.Is(err, model.ErrorNotFound) {
+			message, statusCode = fmt.Sprintf("no deleted job with id %d", id), http.StatusNotFound
+		} else if errors.Is(err, model.ErrorJobExists) {
+			message, statusCode = "a job with the same name already exists", http.StatusConflict
+		} else if errors.Is(err, model.ErrorQuotaExceeded) {
+			message, statusCode = "the quota of the namespace doesn't allow this job", http.StatusForbidden
+		}
+		restoreJobErrorHandler.WriteAndLogError(
+			w,
+			message,
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, job)
+}
+
+func (js *jobServer) triggerJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	job, err := js.storage.TriggerJob(timeoutCtx, model.JobId(id))
+	if err != nil {
+		message, statusCode := fmt.Sprintf("failed to trigger job with id %d", id), http.StatusInternalServerError
+		if errors.Is(err, model.ErrorNotFound) {
+			message, statusCode = fmt.Sprintf("failed to get job by id %d", id), http.StatusNotFound
+		} else if errors.Is(err, model.ErrorJobRunning) {
+			message, statusCode = "the job is already running", http.StatusConflict
+		} else if errors.Is(err, model.ErrorJobPaused) {
+			message, statusCode = "the job is paused", http.StatusConflict
+		}
+		triggerJobErrorHandler.WriteAndLogError(
+			w,
+			message,
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, job)
+}
+
+// requestIdMiddleware assigns each request an id, which is returned to the client and recorded
+// in the audit log. A valid id sent by the client is kept, so requests can be traced across services
+func requestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// loggingMiddleware logs each request along with its request and trace ids
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Infof("%s %s", r.Method, r.RequestURI)
+		logger.WithContext(r.Context()).Infof("%s %s", r.Method, r.RequestURI)
 		next.ServeHTTP(w, r)
 	})
 }
 
-func NewJobServer(storage model.JobStorage, addr string) (*http.Server, error) {
-	server := jobServer{storage, validator.New()}
-	err := validation.RegisterJobValidation(server.validate, storage)
+// NewJobServer creates the API server. Bearer token authentication is disabled if tokenVerifier is nil.
+// The health of schedulers is reported by the health endpoints. Notification rules can't be tested if notifier is nil
+func NewJobServer(
+	storage model.Storage,
+	addr string,
+	config *execution.Config,
+	tokenVerifier *auth.TokenVerifier,
+	schedulers []*scheduler.Scheduler,
+	notifier *notification.Notifier,
+) (*http.Server, error) {
+	server := jobServer{storage, validator.New(), tokenVerifier, notifier}
+	err := validation.RegisterJobValidation(server.validate, storage, config)
+	if err == nil {
+		err = validation.RegisterNotificationValidation(server.validate)
+	}
 	server.validate.RegisterTagNameFunc(func(field reflect.StructField) string {
 		fullJson := field.Tag.Get("json")
 		if fullJson == "-" {
@@ -213,11 +443,62 @@ func NewJobServer(storage model.JobStorage, addr string) (*http.Server, error) {
 
 	router := mux.NewRouter()
 	router.StrictSlash(true)
-	router.HandleFunc("/api/v1/job/", server.createJobHandler).Methods("POST")
-	router.HandleFunc("/api/v1/job/{id:[0-9]+}/", server.getJobHandler).Methods("GET")
-	router.HandleFunc("/api/v1/job/{id:[0-9]+}/", server.deleteJobHandler).Methods("DELETE")
-	router.HandleFunc("/api/v1/job/{name:[a-zA-Z_]\\w*}/", server.getJobByNameHandler).Methods("GET")
-	router.Use(loggingMiddleware)
+	server.registerJobRoutes(router, namespacePrefix)
+	server.registerJobRoutes(router, "/api/v1")
+	router.HandleFunc("/api/v1/key/", server.listAPIKeysHandler).Methods("GET").Name(listAPIKeysRoute)
+	router.HandleFunc("/api/v1/key/", server.createAPIKeyHandler).Methods("POST").Name(createAPIKeyRoute)
+	router.HandleFunc("/api/v1/key/{name}/", server.deleteAPIKeyHandler).Methods("DELETE").Name(deleteAPIKeyRoute)
+	router.HandleFunc("/api/v1/audit/", server.getAuditEntriesHandler).Methods("GET").Name(getAuditEntriesRoute)
+	router.HandleFunc("/api/v1/schedulers/", server.listSchedulersHandler).Methods("GET").Name(listSchedulersRoute)
+	health := healthServer{storage, schedulers, time.Now()}
+	router.HandleFunc("/debug/status", health.statusHandler).Methods("GET").Name(getStatusRoute)
+	router.Use(
+		metricsMiddleware,
+		requestIdMiddleware,
+		tracingMiddleware,
+		loggingMiddleware,
+		server.authenticationMiddleware,
+		authorizationMiddleware,
+		server.jobNamespaceMiddleware,
+	)
 	router.StrictSlash(true)
-	return &http.Server{Addr: addr, Handler: router}, nil
+
+	// Probes are served outside the API router, so they don't need credentials and aren't logged
+	root := mux.NewRouter()
+	root.HandleFunc("/healthz", health.livenessHandler).Methods("GET")
+	root.HandleFunc("/readyz", health.readinessHandler).Methods("GET")
+	root.PathPrefix("/").Handler(router)
+	return &http.Server{Addr: addr, Handler: root}, nil
+}
+
+// registerJobRoutes registers the routes of jobs under a prefix, which may contain the namespace variable
+func (js *jobServer) registerJobRoutes(router *mux.Router, prefix string) {
+	router.HandleFunc(prefix+"/job/", js.createJobHandler).Methods("POST").Name(createJobRoute)
+	router.HandleFunc(prefix+"/job/", js.listJobsHandler).Methods("GET").Name(listJobsRoute)
+	router.HandleFunc(prefix+"/job/", js.deleteJobsHandler).Methods("DELETE").Name(deleteJobsRoute)
+	router.HandleFunc(prefix+"/job/pause/", js.pauseJobsHandler(true)).Methods("POST").Name(pauseJobsRoute)
+	router.HandleFunc(prefix+"/job/resume/", js.pauseJobsHandler(false)).Methods("POST").Name(resumeJobsRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/", js.getJobHandler).Methods("GET").Name(getJobRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/", js.updateJobHandler).Methods("PUT").Name(updateJobRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/", js.deleteJobHandler).Methods("DELETE").Name(deleteJobRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/restore/", js.restoreJobHandler).Methods("POST").Name(restoreJobRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/trigger/", js.triggerJobHandler).Methods("POST").Name(triggerJobRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/revisions/", js.getRevisionsHandler).Methods("GET").Name(getRevisionsRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/revisions/diff/", js.diffRevisionsHandler).Methods("GET").Name(diffRevisionsRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/revisions/{revision:[0-9]+}/", js.getRevisionHandler).Methods("GET").Name(getRevisionRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/revisions/{revision:[0-9]+}/rollback/", js.rollbackJobHandler).Methods("POST").Name(rollbackJobRoute)
+	router.HandleFunc(prefix+"/job/{name:[a-zA-Z_]\\w*}/", js.getJobByNameHandler).Methods("GET").Name(getJobByNameRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/runs/", js.getRunsHandler).Methods("GET").Name(getRunsRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/runs/{runId:[0-9]+}/", js.getRunHandler).Methods("GET").Name(getRunRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/runs/{runId:[0-9]+}/cancel/", js.cancelRunHandler).Methods("POST").Name(cancelRunRoute)
+	router.HandleFunc(prefix+"/job/{id:[0-9]+}/stats/", js.getJobStatsHandler).Methods("GET").Name(getJobStatsRoute)
+	router.HandleFunc(prefix+"/quota/", js.getQuotaHandler).Methods("GET").Name(getQuotaRoute)
+	router.HandleFunc(prefix+"/quota/", js.setQuotaHandler).Methods("PUT").Name(setQuotaRoute)
+	router.HandleFunc(prefix+"/notification/", js.createNotificationRuleHandler).Methods("POST").Name(createNotificationRuleRoute)
+	router.HandleFunc(prefix+"/notification/", js.listNotificationRulesHandler).Methods("GET").Name(listNotificationRulesRoute)
+	router.HandleFunc(prefix+"/notification/{ruleId:[0-9]+}/", js.getNotificationRuleHandler).Methods("GET").Name(getNotificationRuleRoute)
+	router.HandleFunc(prefix+"/notification/{ruleId:[0-9]+}/", js.deleteNotificationRuleHandler).Methods("DELETE").Name(deleteNotificationRuleRoute)
+	router.HandleFunc(prefix+"/notification/{ruleId:[0-9]+}/deliveries/", js.getNotificationDeliveriesHandler).Methods("GET").Name(getNotificationDeliveriesRoute)
+	router.HandleFunc(prefix+"/notification/{ruleId:[0-9]+}/test/", js.testNotificationRuleHandler).Methods("POST").Name(testNotificationRuleRoute)
+	router.HandleFunc(prefix+"/sla/violations/", js.listSLAViolationsHandler).Methods("GET").Name(listSLAViolationsRoute)
 }
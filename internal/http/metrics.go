@@ -0,0 +1,44 @@
+package http
+
+import (
+	"github.com/gorilla/mux"
+	"go-work/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusRecorder records the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sr *statusRecorder) WriteHeader(statusCode int) {
+	sr.statusCode = statusCode
+	sr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// metricsMiddleware records the number and duration of requests by route name
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{w, http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		routeName := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			routeName = route.GetName()
+		}
+		metrics.HTTPRequestsTotal.WithLabelValues(routeName, r.Method, strconv.Itoa(recorder.statusCode)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(routeName, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// NewMetricsServer creates the server of the Prometheus metrics endpoint, which is served separately from
+// the API, so scrapers don't need credentials
+func NewMetricsServer(addr string) *http.Server {
+	router := mux.NewRouter()
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	return &http.Server{Addr: addr, Handler: router}
+}
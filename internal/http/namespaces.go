@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/logging"
+	"go-work/internal/model"
+	"net/http"
+	"strconv"
+)
+
+// namespacePrefix is the prefix of namespace-scoped routes. The same routes without it operate on the
+// default namespace
+const namespacePrefix = "/api/v1/namespaces/{namespace:[a-z0-9][-a-z0-9]*}"
+
+var jobNamespaceErrorHandler = herrors.NewErrorHandler("JobNamespace")
+
+// namespaceFromRequest returns the namespace a request operates on
+func namespaceFromRequest(req *http.Request) string {
+	if namespace := mux.Vars(req)["namespace"]; namespace != "" {
+		return namespace
+	}
+	return model.DefaultNamespace
+}
+
+// jobNamespaceMiddleware responds with 404 Not Found to requests for a job by id which doesn't belong
+// to the request's namespace, so jobs of other namespaces can't be accessed by their ids
+func (js *jobServer) jobNamespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idVar, ok := mux.Vars(r)["id"]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, _ := strconv.ParseInt(idVar, 10, 64)
+		timeoutCtx, cancel := context.WithTimeout(r.Context(), constants.StorageOperationTimeout)
+		defer cancel()
+		namespace, err := js.storage.GetJobNamespace(timeoutCtx, model.JobId(id))
+		if err == nil && namespace != namespaceFromRequest(r) {
+			err = fmt.Errorf("job is in namespace %s: %w", namespace, model.ErrorNotFound)
+		}
+		if err != nil {
+			statusCode := http.StatusNotFound
+			if !errors.Is(err, model.ErrorNotFound) {
+				statusCode = http.StatusInternalServerError
+			}
+			jobNamespaceErrorHandler.WriteAndLogError(
+				w,
+				fmt.Sprintf("failed to get job by id %d", id),
+				err,
+				statusCode,
+				log.Fields{logging.NamespaceField: namespaceFromRequest(r)},
+			)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
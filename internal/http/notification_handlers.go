@@ -0,0 +1,280 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/logging"
+	"go-work/internal/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 1000
+)
+
+var (
+	createNotificationRuleErrorHandler    = herrors.NewErrorHandler("CreateNotificationRule")
+	listNotificationRulesErrorHandler     = herrors.NewErrorHandler("ListNotificationRules")
+	getNotificationRuleErrorHandler       = herrors.NewErrorHandler("GetNotificationRule")
+	deleteNotificationRuleErrorHandler    = herrors.NewErrorHandler("DeleteNotificationRule")
+	getNotificationDeliveriesErrorHandler = herrors.NewErrorHandler("GetNotificationDeliveries")
+	testNotificationRuleErrorHandler      = herrors.NewErrorHandler("TestNotificationRule")
+)
+
+type requestWebhook struct {
+	URL string `json:"url" validate:"required,max=2048,webhookURL"`
+	// Format defaults to json
+	Format model.WebhookFormat `json:"format" validate:"omitempty,oneof=json slack text"`
+	Secret string              `json:"secret" validate:"max=255"`
+}
+
+type requestEmail struct {
+	To []string `json:"to" validate:"required,min=1,max=20,unique,dive,max=254,email"`
+}
+
+// requestNotificationRule sends notifications through either a webhook or email
+type requestNotificationRule struct {
+	// JobId restricts the rule to a job of the namespace, it applies to all of the namespace's jobs if it is 0
+	JobId               model.JobId               `json:"jobId"`
+	Events              []model.NotificationEvent `json:"events" validate:"required,min=1,unique,dive,oneof=failure timeout recovery consecutiveFailures slaViolation"`
+	ConsecutiveFailures uint                      `json:"consecutiveFailures" validate:"max=1000,consecutiveFailures"`
+	Webhook             *requestWebhook           `json:"webhook" validate:"required_without=Email"`
+	Email               *requestEmail             `json:"email" validate:"required_without=Webhook"`
+}
+
+// ruleIdFromRequest parses the ruleId variable, which the routes restrict to digits
+func ruleIdFromRequest(req *http.Request) model.NotificationRuleId {
+	id, _ := strconv.ParseInt(mux.Vars(req)["ruleId"], 10, 64)
+	return model.NotificationRuleId(id)
+}
+
+// getRequestNotificationRule gets the rule of the request's ruleId variable in the request's namespace,
+// writing an error response if it isn't found
+func (js *jobServer) getRequestNotificationRule(
+	w http.ResponseWriter,
+	req *http.Request,
+	errorHandler *herrors.ErrorHandler,
+) (*model.NotificationRule, bool) {
+	id := ruleIdFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	rule, err := js.storage.GetNotificationRule(timeoutCtx, namespaceFromRequest(req), id)
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorNotificationRuleNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
+		errorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get notification rule with id %d", id),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return nil, false
+	}
+	return rule, true
+}
+
+func (js *jobServer) createNotificationRuleHandler(w http.ResponseWriter, req *http.Request) {
+	namespace := namespaceFromRequest(req)
+	rr := requestNotificationRule{}
+	if !decodeJSONBody(w, req, &rr, createNotificationRuleErrorHandler) {
+		return
+	}
+	if err := js.validate.Struct(rr); err != nil {
+		createNotificationRuleErrorHandler.WriteAndLogValidationErrors(
+			w,
+			err.(validator.ValidationErrors),
+			log.Fields{logging.NamespaceField: namespace, logging.JobIdField: rr.JobId, "events": rr.Events},
+		)
+		return
+	}
+
+	// The validator only checks whether struct fields are present, so their exclusivity is checked here
+	if rr.Webhook != nil && rr.Email != nil {
+		createNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			"rules send notifications through either a webhook or email",
+			errors.New("both webhook and email are set"),
+			http.StatusUnprocessableEntity,
+			log.Fields{},
+		)
+		return
+	}
+	if rr.Email != nil && (js.notifier == nil || !js.notifier.EmailEnabled()) {
+		createNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			"email notifications aren't configured",
+			errors.New("no SMTP server"),
+			http.StatusUnprocessableEntity,
+			log.Fields{},
+		)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	if rr.JobId != 0 {
+		// Rules can't be attached to jobs of other namespaces, which aren't accessible through this one
+		jobNamespace, err := js.storage.GetJobNamespace(timeoutCtx, rr.JobId)
+		if err == nil && jobNamespace != namespace {
+			err = fmt.Errorf("job is in namespace %s: %w", jobNamespace, model.ErrorNotFound)
+		}
+		if err != nil {
+			statusCode := http.StatusUnprocessableEntity
+			if !errors.Is(err, model.ErrorNotFound) {
+				statusCode = http.StatusInternalServerError
+			}
+			createNotificationRuleErrorHandler.WriteAndLogError(
+				w,
+				fmt.Sprintf("failed to find job with id %d", rr.JobId),
+				err,
+				statusCode,
+				log.Fields{},
+			)
+			return
+		}
+	}
+
+	rule := model.NotificationRule{
+		Namespace:           namespace,
+		JobId:               rr.JobId,
+		Events:              rr.Events,
+		ConsecutiveFailures: rr.ConsecutiveFailures,
+		CreatedAt:           time.Now(),
+	}
+	if rr.Webhook != nil {
+		rule.Webhook = &model.Webhook{URL: rr.Webhook.URL, Format: rr.Webhook.Format, Secret: rr.Webhook.Secret}
+		if rule.Webhook.Format == "" {
+			rule.Webhook.Format = model.WebhookJSON
+		}
+	}
+	if rr.Email != nil {
+		rule.Email = &model.Email{To: rr.Email.To}
+	}
+	if err := js.storage.CreateNotificationRule(timeoutCtx, &rule); err != nil {
+		createNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			"failed to create notification rule",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, &rule)
+}
+
+func (js *jobServer) listNotificationRulesHandler(w http.ResponseWriter, req *http.Request) {
+	namespace := namespaceFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	rules, err := js.storage.ListNotificationRules(timeoutCtx, namespace)
+	if err != nil {
+		listNotificationRulesErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to list notification rules of namespace %s", namespace),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, rules)
+}
+
+func (js *jobServer) getNotificationRuleHandler(w http.ResponseWriter, req *http.Request) {
+	rule, ok := js.getRequestNotificationRule(w, req, getNotificationRuleErrorHandler)
+	if !ok {
+		return
+	}
+	writeJSON(w, rule)
+}
+
+func (js *jobServer) deleteNotificationRuleHandler(w http.ResponseWriter, req *http.Request) {
+	id := ruleIdFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	err := js.storage.DeleteNotificationRule(timeoutCtx, namespaceFromRequest(req), id)
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorNotificationRuleNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
+		deleteNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to delete notification rule with id %d", id),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (js *jobServer) getNotificationDeliveriesHandler(w http.ResponseWriter, req *http.Request) {
+	limit, ok := parseLimit(w, req, defaultDeliveriesLimit, maxDeliveriesLimit, getNotificationDeliveriesErrorHandler)
+	if !ok {
+		return
+	}
+	rule, ok := js.getRequestNotificationRule(w, req, getNotificationDeliveriesErrorHandler)
+	if !ok {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	deliveries, err := js.storage.GetNotificationDeliveries(timeoutCtx, rule.Id, limit)
+	if err != nil {
+		getNotificationDeliveriesErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get deliveries of notification rule with id %d", rule.Id),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, deliveries)
+}
+
+// testNotificationRuleHandler delivers a test notification through the rule once and responds with
+// the delivery, whether it succeeded or not
+func (js *jobServer) testNotificationRuleHandler(w http.ResponseWriter, req *http.Request) {
+	if js.notifier == nil {
+		testNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			"notifications are disabled",
+			errors.New("no notifier"),
+			http.StatusServiceUnavailable,
+			log.Fields{},
+		)
+		return
+	}
+	rule, ok := js.getRequestNotificationRule(w, req, testNotificationRuleErrorHandler)
+	if !ok {
+		return
+	}
+	delivery, err := js.notifier.Test(req.Context(), rule)
+	if err != nil {
+		testNotificationRuleErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to test notification rule with id %d", rule.Id),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, delivery)
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/model"
+	"net/http"
+)
+
+var (
+	getQuotaErrorHandler = herrors.NewErrorHandler("GetQuota")
+	setQuotaErrorHandler = herrors.NewErrorHandler("SetQuota")
+)
+
+type requestQuota struct {
+	MaxJobs           uint `json:"maxJobs" validate:"max=9223372036854775807"`
+	MaxConcurrentRuns uint `json:"maxConcurrentRuns" validate:"max=9223372036854775807"`
+	MaxTimeoutPerHour uint `json:"maxTimeoutPerHour" validate:"max=9223372036854775807"`
+}
+
+func (js *jobServer) getQuotaHandler(w http.ResponseWriter, req *http.Request) {
+	namespace := namespaceFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	usage, err := js.storage.GetQuotaUsage(timeoutCtx, namespace)
+	if err != nil {
+		getQuotaErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get quota of namespace %s", namespace),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, usage)
+}
+
+func (js *jobServer) setQuotaHandler(w http.ResponseWriter, req *http.Request) {
+	namespace := namespaceFromRequest(req)
+	rq := requestQuota{}
+	if !decodeJSONBody(w, req, &rq, setQuotaErrorHandler) {
+		return
+	}
+	if err := js.validate.Struct(rq); err != nil {
+		setQuotaErrorHandler.WriteAndLogValidationErrors(
+			w,
+			err.(validator.ValidationErrors),
+			log.Fields{"request_quota": rq},
+		)
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	if err := js.storage.SetQuota(timeoutCtx, namespace, model.Quota(rq)); err != nil {
+		setQuotaErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to set quota of namespace %s", namespace),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	usage, err := js.storage.GetQuotaUsage(timeoutCtx, namespace)
+	if err != nil {
+		setQuotaErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get quota of namespace %s", namespace),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, usage)
+}
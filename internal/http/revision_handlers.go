@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/http/validation"
+	"go-work/internal/model"
+	"net/http"
+	"strconv"
+)
+
+var (
+	getRevisionsErrorHandler  = herrors.NewErrorHandler("GetRevisions")
+	getRevisionErrorHandler   = herrors.NewErrorHandler("GetRevision")
+	diffRevisionsErrorHandler = herrors.NewErrorHandler("DiffRevisions")
+	rollbackJobErrorHandler   = herrors.NewErrorHandler("RollbackJob")
+)
+
+type responseDiff struct {
+	From    uint                `json:"from"`
+	To      uint                `json:"to"`
+	Changes []model.FieldChange `json:"changes"`
+}
+
+func (js *jobServer) getRevisionsHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	if _, ok := js.findJob(timeoutCtx, w, model.JobId(id), getRevisionsErrorHandler); !ok {
+		return
+	}
+	revisions, err := js.storage.GetJobRevisions(timeoutCtx, model.JobId(id))
+	if err != nil {
+		getRevisionsErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get revisions of job with id %d", id),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, revisions)
+}
+
+func (js *jobServer) getRevisionHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	revision, _ := strconv.ParseUint(mux.Vars(req)["revision"], 10, 32)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	jobRevision, ok := js.findRevision(timeoutCtx, w, model.JobId(id), uint(revision), getRevisionErrorHandler)
+	if !ok {
+		return
+	}
+	writeJSON(w, jobRevision)
+}
+
+// findRevision gets a revision of a job, writing an error response if it can't be found
+func (js *jobServer) findRevision(
+	ctx context.Context,
+	w http.ResponseWriter,
+	id model.JobId,
+	revision uint,
+	errorHandler *herrors.ErrorHandler,
+) (*model.JobRevision, bool) {
+	jobRevision, err := js.storage.GetJobRevision(ctx, id, revision)
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorRevisionNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
+		errorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get revision %d of job with id %d", revision, id),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return nil, false
+	}
+	return jobRevision, true
+}
+
+func (js *jobServer) diffRevisionsHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	job, ok := js.findJob(timeoutCtx, w, model.JobId(id), diffRevisionsErrorHandler)
+	if !ok {
+		return
+	}
+
+	// By default, the current revision is compared to the previous one
+	revisions := map[string]uint{"from": job.Revision, "to": job.Revision}
+	if job.Revision > 1 {
+		revisions["from"]--
+	}
+	for _, param := range []string{"from", "to"} {
+		value := req.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		revision, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			diffRevisionsErrorHandler.WriteAndLogError(
+				w,
+				fmt.Sprintf("%s must be a revision number", param),
+				err,
+				http.StatusBadRequest,
+				log.Fields{param: value},
+			)
+			return
+		}
+		revisions[param] = uint(revision)
+	}
+
+	from, ok := js.findRevision(timeoutCtx, w, model.JobId(id), revisions["from"], diffRevisionsErrorHandler)
+	if !ok {
+		return
+	}
+	to, ok := js.findRevision(timeoutCtx, w, model.JobId(id), revisions["to"], diffRevisionsErrorHandler)
+	if !ok {
+		return
+	}
+	changes, err := model.DiffJobs(from.Job, to.Job)
+	if err != nil {
+		diffRevisionsErrorHandler.WriteAndLogError(
+			w,
+			"failed to compare revisions",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, responseDiff{from.Revision, to.Revision, changes})
+}
+
+func (js *jobServer) rollbackJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	revision, _ := strconv.ParseUint(mux.Vars(req)["revision"], 10, 32)
+	ctx := req.Context()
+	timeoutCtx, cancel := context.WithTimeout(ctx, constants.StorageOperationTimeout)
+	defer cancel()
+	jobRevision, ok := js.findRevision(timeoutCtx, w, model.JobId(id), uint(revision), rollbackJobErrorHandler)
+	if !ok {
+		return
+	}
+
+	// The previous definition may no longer be allowed, e.g. if the command policy changed since
+	validationCtx := validation.ContextWithUpdatedJob(validation.ContextWithNamespace(ctx, namespaceFromRequest(req)), model.JobId(id))
+	err := js.validate.StructCtx(validationCtx, requestJobFromJob(jobRevision.Job))
+	if err != nil {
+		rollbackJobErrorHandler.WriteAndLogValidationErrors(
+			w,
+			err.(validator.ValidationErrors),
+			log.Fields{"revision": revision},
+		)
+		return
+	}
+
+	job, err := js.storage.RollbackJob(timeoutCtx, model.JobId(id), uint(revision))
+	if err != nil {
+		writeChangeDefinitionError(w, model.JobId(id), err, rollbackJobErrorHandler)
+		return
+	}
+	writeJSON(w, job)
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRunsLimit = 50
+	maxRunsLimit     = 1000
+)
+
+var (
+	getRunsErrorHandler     = herrors.NewErrorHandler("GetRuns")
+	getRunErrorHandler      = herrors.NewErrorHandler("GetRun")
+	cancelRunErrorHandler   = herrors.NewErrorHandler("CancelRun")
+	getJobStatsErrorHandler = herrors.NewErrorHandler("GetJobStats")
+)
+
+// parseLimit parses the limit query parameter, writing an error response if it's invalid
+func parseLimit(
+	w http.ResponseWriter,
+	req *http.Request,
+	defaultLimit uint,
+	maxLimit uint,
+	errorHandler *herrors.ErrorHandler,
+) (uint, bool) {
+	limitParam := req.URL.Query().Get("limit")
+	if limitParam == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.ParseUint(limitParam, 10, 64)
+	if err == nil && (limit == 0 || limit > uint64(maxLimit)) {
+		err = errors.New("limit out of range")
+	}
+	if err != nil {
+		errorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("limit must be an integer between 1 and %d", maxLimit),
+			err,
+			http.StatusBadRequest,
+			log.Fields{"limit": limitParam},
+		)
+		return 0, false
+	}
+	return uint(limit), true
+}
+
+func (js *jobServer) getRunsHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	limit, ok := parseLimit(w, req, defaultRunsLimit, maxRunsLimit, getRunsErrorHandler)
+	if !ok {
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	if _, ok := js.findJob(timeoutCtx, w, model.JobId(id), getRunsErrorHandler); !ok {
+		return
+	}
+	runs, err := js.storage.GetRuns(timeoutCtx, model.JobId(id), limit)
+	if err != nil {
+		getRunsErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get runs of job with id %d", id),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, runs)
+}
+
+func (js *jobServer) getRunHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	runId, _ := strconv.ParseInt(mux.Vars(req)["runId"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	run, err := js.storage.GetRun(timeoutCtx, model.JobId(id), model.RunId(runId))
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if !errors.Is(err, model.ErrorRunNotFound) {
+			statusCode = http.StatusInternalServerError
+		}
+		getRunErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get run %d of job with id %d", runId, id),
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (js *jobServer) cancelRunHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	runId, _ := strconv.ParseInt(mux.Vars(req)["runId"], 10, 64)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	run, err := js.storage.CancelRun(timeoutCtx, model.JobId(id), model.RunId(runId))
+	if err != nil {
+		message, statusCode := fmt.Sprintf("failed to cancel run %d of job with id %d", runId, id), http.StatusInternalServerError
+		if errors.Is(err, model.ErrorRunNotFound) {
+			message, statusCode = fmt.Sprintf("failed to get run %d of job with id %d", runId, id), http.StatusNotFound
+		} else if errors.Is(err, model.ErrorRunNotRunning) {
+			message, statusCode = fmt.Sprintf("run %d of job with id %d is not running", runId, id), http.StatusConflict
+		}
+		cancelRunErrorHandler.WriteAndLogError(
+			w,
+			message,
+			err,
+			statusCode,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (js *jobServer) getJobStatsHandler(w http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	var since time.Time
+	if sinceParam := req.URL.Query().Get("since"); sinceParam != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, sinceParam); err != nil {
+			getJobStatsErrorHandler.WriteAndLogError(
+				w,
+				"since must be an RFC 3339 timestamp",
+				err,
+				http.StatusBadRequest,
+				log.Fields{"since": sinceParam},
+			)
+			return
+		}
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	if _, ok := js.findJob(timeoutCtx, w, model.JobId(id), getJobStatsErrorHandler); !ok {
+		return
+	}
+	stats, err := js.storage.GetJobStats(timeoutCtx, model.JobId(id), since)
+	if err != nil {
+		getJobStatsErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to get stats of job with id %d", id),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, stats)
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"net/http"
+)
+
+const (
+	defaultSchedulersLimit = 100
+	maxSchedulersLimit     = 1000
+)
+
+var listSchedulersErrorHandler = herrors.NewErrorHandler("ListSchedulers")
+
+func (js *jobServer) listSchedulersHandler(w http.ResponseWriter, req *http.Request) {
+	limit, ok := parseLimit(w, req, defaultSchedulersLimit, maxSchedulersLimit, listSchedulersErrorHandler)
+	if !ok {
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	instances, err := js.storage.ListSchedulers(timeoutCtx, limit)
+	if err != nil {
+		listSchedulersErrorHandler.WriteAndLogError(
+			w,
+			"failed to list schedulers",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, instances)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"go-work/internal/labels"
+	"net/http"
+)
+
+var (
+	listJobsErrorHandler   = herrors.NewErrorHandler("ListJobs")
+	pauseJobsErrorHandler  = herrors.NewErrorHandler("PauseJobs")
+	resumeJobsErrorHandler = herrors.NewErrorHandler("ResumeJobs")
+	deleteJobsErrorHandler = herrors.NewErrorHandler("DeleteJobs")
+)
+
+// parseSelector parses the selector query parameter, writing an error response if it's invalid.
+// Bulk operations require a selector, so they can't affect every job by accident
+func parseSelector(
+	w http.ResponseWriter,
+	req *http.Request,
+	required bool,
+	errorHandler *herrors.ErrorHandler,
+) (labels.Selector, bool) {
+	selectorParam := req.URL.Query().Get("selector")
+	selector, err := labels.Parse(selectorParam)
+	if err == nil && required && len(selector) == 0 {
+		err = errors.New("empty selector")
+	}
+	if err != nil {
+		errorHandler.WriteAndLogError(
+			w,
+			"selector must be a valid non-empty label selector",
+			err,
+			http.StatusBadRequest,
+			log.Fields{"selector": selectorParam},
+		)
+		return nil, false
+	}
+	return selector, true
+}
+
+func (js *jobServer) listJobsHandler(w http.ResponseWriter, req *http.Request) {
+	selector, ok := parseSelector(w, req, false, listJobsErrorHandler)
+	if !ok {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	jobs, err := js.storage.ListJobs(timeoutCtx, namespaceFromRequest(req), selector)
+	if err != nil {
+		listJobsErrorHandler.WriteAndLogError(
+			w,
+			"failed to list jobs",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, jobs)
+}
+
+func (js *jobServer) pauseJobsHandler(paused bool) http.HandlerFunc {
+	errorHandler := pauseJobsErrorHandler
+	if !paused {
+		errorHandler = resumeJobsErrorHandler
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		selector, ok := parseSelector(w, req, true, errorHandler)
+		if !ok {
+			return
+		}
+		timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+		defer cancel()
+		jobs, err := js.storage.PauseJobs(timeoutCtx, namespaceFromRequest(req), selector, paused)
+		if err != nil {
+			errorHandler.WriteAndLogError(
+				w,
+				"failed to change jobs",
+				err,
+				http.StatusInternalServerError,
+				log.Fields{},
+			)
+			return
+		}
+		writeJSON(w, jobs)
+	}
+}
+
+func (js *jobServer) deleteJobsHandler(w http.ResponseWriter, req *http.Request) {
+	selector, ok := parseSelector(w, req, true, deleteJobsErrorHandler)
+	if !ok {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	jobs, err := js.storage.DeleteJobs(timeoutCtx, namespaceFromRequest(req), selector)
+	if err != nil {
+		deleteJobsErrorHandler.WriteAndLogError(
+			w,
+			"failed to delete jobs",
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, jobs)
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/http/constants"
+	herrors "go-work/internal/http/errors"
+	"net/http"
+)
+
+var listSLAViolationsErrorHandler = herrors.NewErrorHandler("ListSLAViolations")
+
+// listSLAViolationsHandler lists the jobs of the namespace which currently violate their SLA, whether the
+// checker has alerted about them yet or not
+func (js *jobServer) listSLAViolationsHandler(w http.ResponseWriter, req *http.Request) {
+	namespace := namespaceFromRequest(req)
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), constants.StorageOperationTimeout)
+	defer cancel()
+	violations, err := js.storage.GetSLAViolations(timeoutCtx, namespace)
+	if err != nil {
+		listSLAViolationsErrorHandler.WriteAndLogError(
+			w,
+			fmt.Sprintf("failed to list SLA violations of namespace %s", namespace),
+			err,
+			http.StatusInternalServerError,
+			log.Fields{},
+		)
+		return
+	}
+	writeJSON(w, violations)
+}
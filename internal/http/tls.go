@@ -0,0 +1,93 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// TLSFiles are the PEM files the job server's TLS configuration is loaded from
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS, requiring clients to present a certificate signed by one of its CAs
+	ClientCAFile string
+}
+
+// CertificateReloader provides a TLS configuration whose certificates can be reloaded
+// without restarting the server. Connections already established keep their certificates
+type CertificateReloader struct {
+	files       TLSFiles
+	lock        sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+func NewCertificateReloader(files TLSFiles) (*CertificateReloader, error) {
+	cr := &CertificateReloader{files: files}
+	if err := cr.Reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Reload loads the certificates from their files again. The previous ones are kept if it fails
+func (cr *CertificateReloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(cr.files.CertFile, cr.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed loading server certificate: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if cr.files.ClientCAFile != "" {
+		pem, err := os.ReadFile(cr.files.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed loading client CA certificates: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("failed loading client CA certificates: no certificates found in " + cr.files.ClientCAFile)
+		}
+	}
+
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	cr.certificate = &certificate
+	cr.clientCAs = clientCAs
+	return nil
+}
+
+// TLSConfig returns a configuration which always uses the most recently loaded certificates
+func (cr *CertificateReloader) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return cr.configForClient(config), nil
+	}
+	return config
+}
+
+func (cr *CertificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	return cr.certificate, nil
+}
+
+// configForClient returns a clone of the server's configuration with the most recently loaded client CAs. The
+// clone keeps the protocols negotiated with clients, e.g. HTTP/2
+func (cr *CertificateReloader) configForClient(base *tls.Config) *tls.Config {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+	config := base.Clone()
+	config.GetConfigForClient = nil
+	if cr.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = cr.clientCAs
+	}
+	return config
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"github.com/gorilla/mux"
+	"go-work/internal/requestid"
+	"go-work/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// tracingMiddleware records a span for every request, continuing the trace of the client if
+// it sent a traceparent header. Spans are named after the route, so they can be grouped
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanName := "HTTP " + r.Method
+		routeTemplate := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			spanName = route.GetName()
+			routeTemplate, _ = route.GetPathTemplate()
+		}
+		ctx := tracing.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer.Start(
+			ctx,
+			spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", routeTemplate, r)...),
+			trace.WithAttributes(attribute.String("request.id", requestid.FromContext(r.Context()))),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{w, http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(recorder.statusCode)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(recorder.statusCode, trace.SpanKindServer))
+	})
+}
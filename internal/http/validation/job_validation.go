@@ -6,19 +6,44 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/robfig/cron/v3"
+	"go-work/internal/execution"
 	"go-work/internal/http/constants"
+	"go-work/internal/labels"
 	"go-work/internal/model"
+	"go-work/internal/shell"
 )
 
-func RegisterJobValidation(validate *validator.Validate, storage model.JobStorage) error {
+type (
+	updatedJobKey struct{}
+	namespaceKey  struct{}
+)
+
+// ContextWithUpdatedJob marks validation as part of updating the job with the given id,
+// so the job's own name isn't considered taken
+func ContextWithUpdatedJob(ctx context.Context, id model.JobId) context.Context {
+	return context.WithValue(ctx, updatedJobKey{}, id)
+}
+
+// ContextWithNamespace sets the namespace of the validated job, in which its name must be unique.
+// Without it, the default namespace is used
+func ContextWithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+func RegisterJobValidation(validate *validator.Validate, storage model.JobStorage, config *execution.Config) error {
 	err := validate.RegisterValidationCtx("uniqueName", func(ctx context.Context, fl validator.FieldLevel) bool {
 		timeoutCtx, cancel := context.WithTimeout(ctx, constants.StorageOperationTimeout)
 		defer cancel()
-		_, err := storage.GetJobByName(timeoutCtx, fl.Field().String())
-		if err != nil && errors.Is(err, model.ErrorNotFound) {
-			return true
+		namespace, ok := ctx.Value(namespaceKey{}).(string)
+		if !ok {
+			namespace = model.DefaultNamespace
+		}
+		job, err := storage.GetJobByName(timeoutCtx, namespace, fl.Field().String())
+		if err != nil {
+			return errors.Is(err, model.ErrorNotFound)
 		}
-		return false
+		updatedId, ok := ctx.Value(updatedJobKey{}).(model.JobId)
+		return ok && job.Id == updatedId
 	})
 	if err != nil {
 		return fmt.Errorf("failed registering the \"uniqueName\" validation tag: %w", err)
@@ -29,7 +54,72 @@ func RegisterJobValidation(validate *validator.Validate, storage model.JobStorag
 		return err == nil
 	})
 	if err != nil {
-		err = fmt.Errorf("failed registering the \"crontabString\" validation tag: %w", err)
+		return fmt.Errorf("failed registering the \"crontabString\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("onlyInMode", func(fl validator.FieldLevel) bool {
+		if fl.Field().IsZero() {
+			return true
+		}
+		mode := fl.Parent().FieldByName("Mode")
+		return mode.IsValid() && mode.String() == fl.Param()
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"onlyInMode\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("script", func(fl validator.FieldLevel) bool {
+		return shell.ValidateScript(fl.Field().String()) == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"script\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("interpreter", func(fl validator.FieldLevel) bool {
+		_, err := config.CheckInterpreter(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"interpreter\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("allowedCommand", func(fl validator.FieldLevel) bool {
+		_, err := config.CheckCommand(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"allowedCommand\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("labels", func(fl validator.FieldLevel) bool {
+		jobLabels, ok := fl.Field().Interface().(map[string]string)
+		return ok && labels.Validate(jobLabels) == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"labels\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("namespace", func(fl validator.FieldLevel) bool {
+		return model.ValidateNamespace(fl.Field().String()) == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"namespace\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("allowedUser", func(fl validator.FieldLevel) bool {
+		_, err := config.ResolveUser(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"allowedUser\" validation tag: %w", err)
+	}
+
+	err = validate.RegisterValidation("allowedGroup", func(fl validator.FieldLevel) bool {
+		_, err := config.ResolveGroup(fl.Field().String())
+		return err == nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed registering the \"allowedGroup\" validation tag: %w", err)
 	}
 	return err
 }
@@ -0,0 +1,35 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"go-work/internal/model"
+	"net/url"
+)
+
+func RegisterNotificationValidation(validate *validator.Validate) error {
+	err := validate.RegisterValidation("webhookURL", func(fl validator.FieldLevel) bool {
+		webhookURL, err := url.Parse(fl.Field().String())
+		return err == nil && (webhookURL.Scheme == "http" || webhookURL.Scheme == "https") && webhookURL.Host != ""
+	})
+	if err != nil {
+		return fmt.Errorf("failed registering the \"webhookURL\" validation tag: %w", err)
+	}
+
+	// The threshold of consecutive failures must be set exactly if the rule is triggered by them
+	err = validate.RegisterValidation("consecutiveFailures", func(fl validator.FieldLevel) bool {
+		events, ok := fl.Parent().FieldByName("Events").Interface().([]model.NotificationEvent)
+		if !ok {
+			return false
+		}
+		triggered := false
+		for _, event := range events {
+			triggered = triggered || event == model.NotifyOnConsecutiveFailures
+		}
+		return triggered == !fl.Field().IsZero()
+	})
+	if err != nil {
+		err = fmt.Errorf("failed registering the \"consecutiveFailures\" validation tag: %w", err)
+	}
+	return err
+}
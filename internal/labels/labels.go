@@ -0,0 +1,63 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	MaxLabels         = 64
+	maxNameLength     = 63
+	maxPrefixLength   = 253
+	maxValueLength    = 63
+	nameDescription   = "must be at most 63 characters, consisting of alphanumerics, '-', '_' and '.', and start and end with an alphanumeric"
+	prefixDescription = "must be a lowercase DNS subdomain of at most 253 characters"
+)
+
+var (
+	namePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	prefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidateKey checks a label key, which is a name optionally preceded by a DNS subdomain
+// prefix and a slash, e.g. "example.com/team"
+func ValidateKey(key string) error {
+	name := key
+	if prefix, rest, found := strings.Cut(key, "/"); found {
+		if len(prefix) > maxPrefixLength || !prefixPattern.MatchString(prefix) {
+			return fmt.Errorf("invalid label key \"%s\": prefix %s", key, prefixDescription)
+		}
+		name = rest
+	}
+	if len(name) > maxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid label key \"%s\": name %s", key, nameDescription)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value, which follows the rules of key names but may be empty
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxValueLength || !namePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value \"%s\": value %s", value, nameDescription)
+	}
+	return nil
+}
+
+func Validate(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+	for key, value := range labels {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+		if err := ValidateValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
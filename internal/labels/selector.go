@@ -0,0 +1,135 @@
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is a condition on a single label. Values are empty for Exists and DoesNotExist
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector matches labels satisfying all of its requirements. An empty selector matches everything
+type Selector []Requirement
+
+// Parse parses a Kubernetes-style label selector, a comma-separated list of requirements:
+//
+//	key=value, key==value  the label is set to the value
+//	key!=value             the label isn't set to the value, or is absent
+//	key in (v1,v2)         the label is set to one of the values
+//	key notin (v1,v2)      the label isn't set to any of the values, or is absent
+//	key                    the label is present
+//	!key                   the label is absent
+func Parse(selector string) (Selector, error) {
+	parts, err := splitRequirements(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Selector, 0, len(parts))
+	for _, part := range parts {
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid requirement \"%s\": %w", part, err)
+		}
+		result = append(result, requirement)
+	}
+	return result, nil
+}
+
+// splitRequirements splits a selector on commas outside of parentheses
+func splitRequirements(selector string) ([]string, error) {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("nested parentheses in selector \"%s\"", selector)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in selector \"%s\"", selector)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in selector \"%s\"", selector)
+	}
+	parts = append(parts, selector[start:])
+
+	trimmed := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			if len(parts) == 1 {
+				return trimmed, nil
+			}
+			return nil, fmt.Errorf("empty requirement in selector \"%s\"", selector)
+		}
+		trimmed = append(trimmed, part)
+	}
+	return trimmed, nil
+}
+
+func parseRequirement(part string) (Requirement, error) {
+	if strings.HasPrefix(part, "!") && !strings.HasPrefix(part, "!=") {
+		key := strings.TrimSpace(part[1:])
+		return Requirement{Key: key, Operator: DoesNotExist}, ValidateKey(key)
+	}
+	for _, operator := range []string{"!=", "==", "="} {
+		if key, value, found := strings.Cut(part, operator); found {
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			op := Equals
+			if operator == "!=" {
+				op = NotEquals
+			}
+			if err := ValidateKey(key); err != nil {
+				return Requirement{}, err
+			}
+			return Requirement{Key: key, Operator: op, Values: []string{value}}, ValidateValue(value)
+		}
+	}
+	if open := strings.Index(part, "("); open >= 0 {
+		if !strings.HasSuffix(part, ")") {
+			return Requirement{}, fmt.Errorf("expected a closing parenthesis at the end")
+		}
+		fields := strings.Fields(part[:open])
+		if len(fields) != 2 || (fields[1] != string(In) && fields[1] != string(NotIn)) {
+			return Requirement{}, fmt.Errorf("expected \"key in (values)\" or \"key notin (values)\"")
+		}
+		if err := ValidateKey(fields[0]); err != nil {
+			return Requirement{}, err
+		}
+		values := make([]string, 0)
+		for _, value := range strings.Split(part[open+1:len(part)-1], ",") {
+			value = strings.TrimSpace(value)
+			if err := ValidateValue(value); err != nil {
+				return Requirement{}, err
+			}
+			values = append(values, value)
+		}
+		return Requirement{Key: fields[0], Operator: Operator(fields[1]), Values: values}, nil
+	}
+	return Requirement{Key: part, Operator: Exists}, ValidateKey(part)
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/model"
+	"go-work/internal/requestid"
+	"go-work/internal/tracing"
+)
+
+// Names of the fields identifying what an entry is about. They are the same in every package, so all entries
+// of a job, run, scheduler or request can be found with one query
+const (
+	PackageField     = "package"
+	NamespaceField   = "namespace"
+	JobIdField       = "job_id"
+	JobNameField     = "job_name"
+	RunIdField       = "run_id"
+	SchedulerIdField = "scheduler_id"
+	RequestIdField   = "request_id"
+	TraceIdField     = "trace_id"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config controls the format and verbosity of the app's logs
+type Config struct {
+	// Format is FormatText or FormatJSON
+	Format string
+	// Level is the minimum level of logged entries, e.g. info
+	Level string
+}
+
+// root is the logger all package loggers write through
+var root = newRoot()
+
+func newRoot() *log.Logger {
+	logger := log.New()
+	logger.AddHook(contextHook{})
+	return logger
+}
+
+// Configure sets the format and level of all package loggers
+func Configure(config Config) error {
+	level, err := log.ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	var formatter log.Formatter
+	switch config.Format {
+	case FormatText:
+		formatter = &log.TextFormatter{}
+	case FormatJSON:
+		formatter = &log.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %s", config.Format)
+	}
+	root.SetLevel(level)
+	root.SetFormatter(formatter)
+	return nil
+}
+
+// ForPackage returns the logger of a package, whose entries carry the package's name
+func ForPackage(name string) *log.Entry {
+	return root.WithField(PackageField, name)
+}
+
+// JobFields returns the fields identifying a job
+func JobFields(job *model.Job) log.Fields {
+	return log.Fields{
+		NamespaceField: job.Namespace,
+		JobIdField:     job.Id,
+		JobNameField:   job.Name,
+	}
+}
+
+// contextHook adds the ids of the request and trace an entry was logged in to the entry, if it was given a context
+type contextHook struct{}
+
+func (contextHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (contextHook) Fire(entry *log.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := requestid.FromContext(entry.Context); id != "" {
+		entry.Data[RequestIdField] = id
+	}
+	if id := tracing.TraceId(entry.Context); id != "" {
+		entry.Data[TraceIdField] = id
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"context"
+	"go-work/internal/auth"
+	"go-work/internal/labels"
+	"go-work/internal/model"
+	"time"
+)
+
+// InstrumentStorage returns a storage which records the duration of every operation of storage
+func InstrumentStorage(storage model.Storage) model.Storage {
+	return &instrumentedStorage{storage}
+}
+
+type instrumentedStorage struct {
+	storage model.Storage
+}
+
+// observe records the duration of an operation which started at start. It is deferred with
+// a pointer to the operation's error, which is only known once it returns
+func observe(operation string, start time.Time, err *error) {
+	outcome := "success"
+	if *err != nil {
+		outcome = "error"
+	}
+	StorageOperationDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (is *instrumentedStorage) CreateJob(ctx context.Context, job *model.Job) (id model.JobId, err error) {
+	defer observe("CreateJob", time.Now(), &err)
+	return is.storage.CreateJob(ctx, job)
+}
+
+func (is *instrumentedStorage) GetJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	defer observe("GetJob", time.Now(), &err)
+	return is.storage.GetJob(ctx, id)
+}
+
+func (is *instrumentedStorage) DeleteJob(ctx context.Context, id model.JobId) (err error) {
+	defer observe("DeleteJob", time.Now(), &err)
+	return is.storage.DeleteJob(ctx, id)
+}
+
+func (is *instrumentedStorage) RestoreJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	defer observe("RestoreJob", time.Now(), &err)
+	return is.storage.RestoreJob(ctx, id)
+}
+
+func (is *instrumentedStorage) UpdateJob(ctx context.Context, job *model.Job) (updated *model.Job, err error) {
+	defer observe("UpdateJob", time.Now(), &err)
+	return is.storage.UpdateJob(ctx, job)
+}
+
+func (is *instrumentedStorage) RollbackJob(ctx context.Context, id model.JobId, revision uint) (job *model.Job, err error) {
+	defer observe("RollbackJob", time.Now(), &err)
+	return is.storage.RollbackJob(ctx, id, revision)
+}
+
+func (is *instrumentedStorage) GetJobRevisions(ctx context.Context, id model.JobId) (revisions []*model.JobRevision, err error) {
+	defer observe("GetJobRevisions", time.Now(), &err)
+	return is.storage.GetJobRevisions(ctx, id)
+}
+
+func (is *instrumentedStorage) GetJobRevision(ctx context.Context, id model.JobId, revision uint) (jobRevision *model.JobRevision, err error) {
+	defer observe("GetJobRevision", time.Now(), &err)
+	return is.storage.GetJobRevision(ctx, id, revision)
+}
+
+func (is *instrumentedStorage) PurgeDeletedJobs(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	defer observe("PurgeDeletedJobs", time.Now(), &err)
+	return is.storage.PurgeDeletedJobs(ctx, deletedBefore)
+}
+
+func (is *instrumentedStorage) GetJobByName(ctx context.Context, namespace string, name string) (job *model.Job, err error) {
+	defer observe("GetJobByName", time.Now(), &err)
+	return is.storage.GetJobByName(ctx, namespace, name)
+}
+
+func (is *instrumentedStorage) GetJobNamespace(ctx context.Context, id model.JobId) (namespace string, err error) {
+	defer observe("GetJobNamespace", time.Now(), &err)
+	return is.storage.GetJobNamespace(ctx, id)
+}
+
+func (is *instrumentedStorage) ListJobs(ctx context.Context, namespace string, selector labels.Selector) (jobs []*model.Job, err error) {
+	defer observe("ListJobs", time.Now(), &err)
+	return is.storage.ListJobs(ctx, namespace, selector)
+}
+
+func (is *instrumentedStorage) PauseJobs(ctx context.Context, namespace string, selector labels.Selector, paused bool) (jobs []*model.Job, err error) {
+	defer observe("PauseJobs", time.Now(), &err)
+	return is.storage.PauseJobs(ctx, namespace, selector, paused)
+}
+
+func (is *instrumentedStorage) DeleteJobs(ctx context.Context, namespace string, selector labels.Selector) (jobs []*model.Job, err error) {
+	defer observe("DeleteJobs", time.Now(), &err)
+	return is.storage.DeleteJobs(ctx, namespace, selector)
+}
+
+func (is *instrumentedStorage) MarkDueJobsRunning(ctx context.Context) (jobs []*model.Job, err error) {
+	defer observe("MarkDueJobsRunning", time.Now(), &err)
+	return is.storage.MarkDueJobsRunning(ctx)
+}
+
+func (is *instrumentedStorage) MarkJobDone(ctx context.Context, job *model.Job) (err error) {
+	defer observe("MarkJobDone", time.Now(), &err)
+	return is.storage.MarkJobDone(ctx, job)
+}
+
+func (is *instrumentedStorage) TriggerJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	defer observe("TriggerJob", time.Now(), &err)
+	return is.storage.TriggerJob(ctx, id)
+}
+
+func (is *instrumentedStorage) StartRun(ctx context.Context, job *model.Job, schedulerId model.SchedulerId, startTime time.Time) (run *model.Run, err error) {
+	defer observe("StartRun", time.Now(), &err)
+	return is.storage.StartRun(ctx, job, schedulerId, startTime)
+}
+
+func (is *instrumentedStorage) FinishRun(ctx context.Context, run *model.Run) (err error) {
+	defer observe("FinishRun", time.Now(), &err)
+	return is.storage.FinishRun(ctx, run)
+}
+
+func (is *instrumentedStorage) GetRuns(ctx context.Context, jobId model.JobId, limit uint) (runs []*model.Run, err error) {
+	defer observe("GetRuns", time.Now(), &err)
+	return is.storage.GetRuns(ctx, jobId, limit)
+}
+
+func (is *instrumentedStorage) GetRun(ctx context.Context, jobId model.JobId, id model.RunId) (run *model.Run, err error) {
+	defer observe("GetRun", time.Now(), &err)
+	return is.storage.GetRun(ctx, jobId, id)
+}
+
+func (is *instrumentedStorage) CancelRun(ctx context.Context, jobId model.JobId, id model.RunId) (run *model.Run, err error) {
+	defer observe("CancelRun", time.Now(), &err)
+	return is.storage.CancelRun(ctx, jobId, id)
+}
+
+func (is *instrumentedStorage) GetCancelledRuns(ctx context.Context, ids []model.RunId) (cancelled []model.RunId, err error) {
+	defer observe("GetCancelledRuns", time.Now(), &err)
+	return is.storage.GetCancelledRuns(ctx, ids)
+}
+
+func (is *instrumentedStorage) GetJobStats(ctx context.Context, jobId model.JobId, since time.Time) (stats *model.JobStats, err error) {
+	defer observe("GetJobStats", time.Now(), &err)
+	return is.storage.GetJobStats(ctx, jobId, since)
+}
+
+func (is *instrumentedStorage) CreateAPIKey(ctx context.Context, name string, role auth.Role, namespaces []string, keyHash []byte) (key *model.APIKey, err error) {
+	defer observe("CreateAPIKey", time.Now(), &err)
+	return is.storage.CreateAPIKey(ctx, name, role, namespaces, keyHash)
+}
+
+func (is *instrumentedStorage) AuthenticateAPIKey(ctx context.Context, keyHash []byte) (key *model.APIKey, err error) {
+	defer observe("AuthenticateAPIKey", time.Now(), &err)
+	return is.storage.AuthenticateAPIKey(ctx, keyHash)
+}
+
+func (is *instrumentedStorage) RecordAPIKeyUse(ctx context.Context, key *model.APIKey) (err error) {
+	defer observe("RecordAPIKeyUse", time.Now(), &err)
+	return is.storage.RecordAPIKeyUse(ctx, key)
+}
+
+func (is *instrumentedStorage) ListAPIKeys(ctx context.Context) (keys []*model.APIKey, err error) {
+	defer observe("ListAPIKeys", time.Now(), &err)
+	return is.storage.ListAPIKeys(ctx)
+}
+
+func (is *instrumentedStorage) DeleteAPIKey(ctx context.Context, name string) (err error) {
+	defer observe("DeleteAPIKey", time.Now(), &err)
+	return is.storage.DeleteAPIKey(ctx, name)
+}
+
+func (is *instrumentedStorage) GetAuditEntries(ctx context.Context, filter model.AuditFilter) (entries []*model.AuditEntry, err error) {
+	defer observe("GetAuditEntries", time.Now(), &err)
+	return is.storage.GetAuditEntries(ctx, filter)
+}
+
+func (is *instrumentedStorage) GetQuotaUsage(ctx context.Context, namespace string) (usage *model.QuotaUsage, err error) {
+	defer observe("GetQuotaUsage", time.Now(), &err)
+	return is.storage.GetQuotaUsage(ctx, namespace)
+}
+
+func (is *instrumentedStorage) SetQuota(ctx context.Context, namespace string, quota model.Quota) (err error) {
+	defer observe("SetQuota", time.Now(), &err)
+	return is.storage.SetQuota(ctx, namespace, quota)
+}
+
+func (is *instrumentedStorage) CheckHealth(ctx context.Context) (err error) {
+	defer observe("CheckHealth", time.Now(), &err)
+	return is.storage.CheckHealth(ctx)
+}
+
+func (is *instrumentedStorage) RegisterScheduler(ctx context.Context, instance *model.SchedulerInstance) (err error) {
+	defer observe("RegisterScheduler", time.Now(), &err)
+	return is.storage.RegisterScheduler(ctx, instance)
+}
+
+func (is *instrumentedStorage) HeartbeatScheduler(ctx context.Context, id model.SchedulerId) (err error) {
+	defer observe("HeartbeatScheduler", time.Now(), &err)
+	return is.storage.HeartbeatScheduler(ctx, id)
+}
+
+func (is *instrumentedStorage) StopScheduler(ctx context.Context, id model.SchedulerId) (err error) {
+	defer observe("StopScheduler", time.Now(), &err)
+	return is.storage.StopScheduler(ctx, id)
+}
+
+func (is *instrumentedStorage) ListSchedulers(ctx context.Context, limit uint) (instances []*model.SchedulerInstance, err error) {
+	defer observe("ListSchedulers", time.Now(), &err)
+	return is.storage.ListSchedulers(ctx, limit)
+}
+
+func (is *instrumentedStorage) CreateNotificationRule(ctx context.Context, rule *model.NotificationRule) (err error) {
+	defer observe("CreateNotificationRule", time.Now(), &err)
+	return is.storage.CreateNotificationRule(ctx, rule)
+}
+
+func (is *instrumentedStorage) GetNotificationRule(ctx context.Context, namespace string, id model.NotificationRuleId) (rule *model.NotificationRule, err error) {
+	defer observe("GetNotificationRule", time.Now(), &err)
+	return is.storage.GetNotificationRule(ctx, namespace, id)
+}
+
+func (is *instrumentedStorage) ListNotificationRules(ctx context.Context, namespace string) (rules []*model.NotificationRule, err error) {
+	defer observe("ListNotificationRules", time.Now(), &err)
+	return is.storage.ListNotificationRules(ctx, namespace)
+}
+
+func (is *instrumentedStorage) DeleteNotificationRule(ctx context.Context, namespace string, id model.NotificationRuleId) (err error) {
+	defer observe("DeleteNotificationRule", time.Now(), &err)
+	return is.storage.DeleteNotificationRule(ctx, namespace, id)
+}
+
+func (is *instrumentedStorage) GetJobNotificationRules(ctx context.Context, job *model.Job) (rules []*model.NotificationRule, err error) {
+	defer observe("GetJobNotificationRules", time.Now(), &err)
+	return is.storage.GetJobNotificationRules(ctx, job)
+}
+
+func (is *instrumentedStorage) CreateNotificationDelivery(ctx context.Context, delivery *model.NotificationDelivery) (err error) {
+	defer observe("CreateNotificationDelivery", time.Now(), &err)
+	return is.storage.CreateNotificationDelivery(ctx, delivery)
+}
+
+func (is *instrumentedStorage) UpdateNotificationDelivery(ctx context.Context, delivery *model.NotificationDelivery) (err error) {
+	defer observe("UpdateNotificationDelivery", time.Now(), &err)
+	return is.storage.UpdateNotificationDelivery(ctx, delivery)
+}
+
+func (is *instrumentedStorage) GetNotificationDeliveries(ctx context.Context, ruleId model.NotificationRuleId, limit uint) (deliveries []*model.NotificationDelivery, err error) {
+	defer observe("GetNotificationDeliveries", time.Now(), &err)
+	return is.storage.GetNotificationDeliveries(ctx, ruleId, limit)
+}
+
+func (is *instrumentedStorage) GetSLAViolations(ctx context.Context, namespace string) (violations []*model.SLAViolation, err error) {
+	defer observe("GetSLAViolations", time.Now(), &err)
+	return is.storage.GetSLAViolations(ctx, namespace)
+}
+
+func (is *instrumentedStorage) MarkSLAViolations(ctx context.Context) (violations []*model.SLAViolation, err error) {
+	defer observe("MarkSLAViolations", time.Now(), &err)
+	return is.storage.MarkSLAViolations(ctx)
+}
+
+func (is *instrumentedStorage) ReclaimJobs(ctx context.Context, grace time.Duration) (reclaimed []*model.ReclaimedJob, err error) {
+	defer observe("ReclaimJobs", time.Now(), &err)
+	return is.storage.ReclaimJobs(ctx, grace)
+}
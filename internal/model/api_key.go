@@ -0,0 +1,35 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"go-work/internal/auth"
+	"time"
+)
+
+type APIKeyId int64
+
+type APIKey struct {
+	Id   APIKeyId  `json:"id"`
+	Name string    `json:"name"`
+	Role auth.Role `json:"role"`
+	// Namespaces the key is restricted to, empty if it can access all namespaces
+	Namespaces []string   `json:"namespaces,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+var (
+	ErrorAPIKeyNotFound = errors.New("API key not found")
+	ErrorAPIKeyExists   = errors.New("API key with this name already exists")
+)
+
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, name string, role auth.Role, namespaces []string, keyHash []byte) (*APIKey, error)
+	// AuthenticateAPIKey finds the API key with the given hash
+	AuthenticateAPIKey(ctx context.Context, keyHash []byte) (*APIKey, error)
+	// RecordAPIKeyUse records that the key was used now. The last use is only updated once a minute
+	RecordAPIKeyUse(ctx context.Context, key *APIKey) error
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	DeleteAPIKey(ctx context.Context, name string) error
+}
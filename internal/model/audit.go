@@ -0,0 +1,54 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type AuditEntryId int64
+
+// AuditAction is a change made to a job definition, or a run of a job requested through the API
+type AuditAction string
+
+const (
+	JobCreated    AuditAction = "create"
+	JobUpdated    AuditAction = "update"
+	JobRolledBack AuditAction = "rollback"
+	JobDeleted    AuditAction = "delete"
+	JobRestored   AuditAction = "restore"
+	JobPurged     AuditAction = "purge"
+	JobPaused     AuditAction = "pause"
+	JobResumed    AuditAction = "resume"
+	JobTriggered  AuditAction = "trigger"
+)
+
+// SystemActor is recorded as the actor of changes not made by an authenticated client
+const SystemActor = "system"
+
+// AuditEntry records a change of a job definition or a triggered run. Before is nil for created jobs
+// and After is nil for deleted and purged jobs
+type AuditEntry struct {
+	Id        AuditEntryId `json:"id"`
+	Time      time.Time    `json:"time"`
+	Actor     string       `json:"actor"`
+	RequestId string       `json:"requestId,omitempty"`
+	Action    AuditAction  `json:"action"`
+	JobId     JobId        `json:"jobId"`
+	JobName   string       `json:"jobName"`
+	Before    *Job         `json:"before,omitempty"`
+	After     *Job         `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero values match any entry
+type AuditFilter struct {
+	JobId JobId
+	Actor string
+	Limit uint
+}
+
+// AuditStorage reads the audit log. Entries are written by the job storage as part
+// of the changes they record, and are never updated or deleted
+type AuditStorage interface {
+	// GetAuditEntries returns matching entries, the most recent first
+	GetAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error)
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"context"
+)
+
+// SchemaVersion is the version of the database schema the app works with. It must match the version
+// recorded in the schemaversion table by build/postgres/initialize.sh
+const SchemaVersion = 9
+
+type HealthStorage interface {
+	// CheckHealth checks that the database is reachable and its schema is up-to-date
+	CheckHealth(ctx context.Context) error
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultNamespace holds jobs created through the routes which aren't scoped to a namespace
+const DefaultNamespace = "default"
+
+const maxNamespaceLength = 63
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateNamespace checks that a namespace name is a DNS label: at most 63 lowercase
+// alphanumerics and '-', starting and ending with an alphanumeric
+func ValidateNamespace(namespace string) error {
+	if len(namespace) > maxNamespaceLength || !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf(
+			"invalid namespace \"%s\": must be at most 63 lowercase alphanumerics and '-', "+
+				"starting and ending with an alphanumeric",
+			namespace,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type NotificationRuleId int64
+
+type NotificationDeliveryId int64
+
+// NotificationEvent is an outcome of runs which notification rules can be triggered by
+type NotificationEvent string
+
+const (
+	// NotifyOnFailure is triggered by every failed run, including ones which timed out
+	NotifyOnFailure NotificationEvent = "failure"
+	// NotifyOnTimeout is triggered by runs terminated after exceeding the job's timeout
+	NotifyOnTimeout NotificationEvent = "timeout"
+	// NotifyOnRecovery is triggered by a successful run following a failed one
+	NotifyOnRecovery NotificationEvent = "recovery"
+	// NotifyOnConsecutiveFailures is triggered when the number of consecutive failed runs reaches the
+	// rule's threshold. It isn't triggered again until the job has succeeded in between
+	NotifyOnConsecutiveFailures NotificationEvent = "consecutiveFailures"
+	// NotifyOnSLAViolation is triggered when a job hasn't succeeded within its maxSuccessInterval. It isn't
+	// triggered again until the job has succeeded in between
+	NotifyOnSLAViolation NotificationEvent = "slaViolation"
+	// NotificationTest is only sent when a rule is tested through the API
+	NotificationTest NotificationEvent = "test"
+)
+
+// WebhookFormat is the format of the JSON documents a webhook receives
+type WebhookFormat string
+
+const (
+	// WebhookJSON is the notification payload itself
+	WebhookJSON WebhookFormat = "json"
+	// WebhookSlack is a message with an attachment for Slack and Mattermost incoming webhooks
+	WebhookSlack WebhookFormat = "slack"
+	// WebhookText is a message with only a text field, which most chat services' incoming webhooks accept
+	WebhookText WebhookFormat = "text"
+)
+
+// Webhook receives notifications as JSON documents in HTTP POST requests. Requests are signed with
+// the secret, which is never returned by the API
+type Webhook struct {
+	URL    string        `json:"url"`
+	Format WebhookFormat `json:"format"`
+	Secret string        `json:"-"`
+}
+
+// Email sends notifications as emails through the SMTP server configured for the app
+type Email struct {
+	To []string `json:"to"`
+}
+
+// NotificationRule sends notifications about runs of a job, or all jobs of its namespace if JobId is 0,
+// through exactly one channel, a webhook or email
+type NotificationRule struct {
+	Id        NotificationRuleId  `json:"id"`
+	Namespace string              `json:"namespace"`
+	JobId     JobId               `json:"jobId,omitempty"`
+	Events    []NotificationEvent `json:"events"`
+	// ConsecutiveFailures is the threshold of the consecutiveFailures event
+	ConsecutiveFailures uint      `json:"consecutiveFailures,omitempty"`
+	Webhook             *Webhook  `json:"webhook,omitempty"`
+	Email               *Email    `json:"email,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// NotificationDelivery records the delivery of a notification through a rule, including its retries
+type NotificationDelivery struct {
+	Id     NotificationDeliveryId `json:"id"`
+	RuleId NotificationRuleId     `json:"ruleId"`
+	JobId  JobId                  `json:"jobId,omitempty"`
+	RunId  RunId                  `json:"runId,omitempty"`
+	Events []NotificationEvent    `json:"events"`
+	Status DeliveryStatus         `json:"status"`
+	// Attempts is the number of times delivery was attempted
+	Attempts uint `json:"attempts"`
+	// ResponseCode is the status code of the last attempt's response, if any. It is an HTTP status code
+	// for webhooks and an SMTP reply code for email
+	ResponseCode *int       `json:"responseCode,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+}
+
+var ErrorNotificationRuleNotFound = errors.New("notification rule not found")
+
+type NotificationStorage interface {
+	CreateNotificationRule(ctx context.Context, rule *NotificationRule) error
+	GetNotificationRule(ctx context.Context, namespace string, id NotificationRuleId) (*NotificationRule, error)
+	// ListNotificationRules returns the rules of the namespace, ordered by id
+	ListNotificationRules(ctx context.Context, namespace string) ([]*NotificationRule, error)
+	DeleteNotificationRule(ctx context.Context, namespace string, id NotificationRuleId) error
+	// GetJobNotificationRules returns the rules of the job and the rules of its namespace which apply to all jobs
+	GetJobNotificationRules(ctx context.Context, job *Job) ([]*NotificationRule, error)
+	CreateNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error
+	// UpdateNotificationDelivery records the outcome of the latest delivery attempt
+	UpdateNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error
+	// GetNotificationDeliveries returns the deliveries of a rule, the most recent first
+	GetNotificationDeliveries(ctx context.Context, ruleId NotificationRuleId, limit uint) ([]*NotificationDelivery, error)
+}
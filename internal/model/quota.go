@@ -0,0 +1,35 @@
+package model
+
+import (
+	"context"
+	"errors"
+)
+
+// Quota limits the jobs of a namespace. Zero values mean that the resource is unlimited
+type Quota struct {
+	// MaxJobs limits the number of jobs in the namespace, excluding deleted ones
+	MaxJobs uint `json:"maxJobs"`
+	// MaxConcurrentRuns limits the number of jobs of the namespace which run at the same time
+	MaxConcurrentRuns uint `json:"maxConcurrentRuns"`
+	// MaxTimeoutPerHour limits the sum of the timeouts in seconds of the jobs claimed to run in the last hour
+	MaxTimeoutPerHour uint `json:"maxTimeoutPerHour"`
+}
+
+// QuotaUsage is the quota of a namespace along with the usage of its resources
+type QuotaUsage struct {
+	Namespace string `json:"namespace"`
+	Quota     Quota  `json:"quota"`
+	Jobs      uint   `json:"jobs"`
+	// RunningJobs is the number of jobs which are currently running
+	RunningJobs uint `json:"runningJobs"`
+	// TimeoutLastHour is the sum of the timeouts of the jobs claimed to run in the last hour
+	TimeoutLastHour uint `json:"timeoutLastHour"`
+}
+
+var ErrorQuotaExceeded = errors.New("namespace quota exceeded")
+
+type QuotaStorage interface {
+	GetQuotaUsage(ctx context.Context, namespace string) (*QuotaUsage, error)
+	// SetQuota replaces the quota of the namespace. A quota with only zero values removes it
+	SetQuota(ctx context.Context, namespace string, quota Quota) error
+}
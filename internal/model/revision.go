@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+)
+
+var ErrorRevisionNotFound = errors.New("job revision not found")
+
+// JobRevision is an immutable snapshot of a job's definition, created whenever it changes
+type JobRevision struct {
+	JobId     JobId     `json:"jobId"`
+	Revision  uint      `json:"revision"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     string    `json:"actor"`
+	Job       *Job      `json:"job"`
+}
+
+// FieldChange is a difference between two job definitions. Nested fields are
+// separated by dots, e.g. "limits.cpuSeconds". Absent values are nil
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// DiffJobs returns the fields of the job definitions which differ, sorted by name.
+// The id, revision and paused state aren't part of the definition and are ignored
+func DiffJobs(from *Job, to *Job) ([]FieldChange, error) {
+	fromFields, err := flattenJob(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := flattenJob(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]FieldChange, 0)
+	for field, fromValue := range fromFields {
+		if toValue, ok := toFields[field]; !ok || !reflect.DeepEqual(fromValue, toValue) {
+			changes = append(changes, FieldChange{field, fromValue, toFields[field]})
+		}
+	}
+	for field, toValue := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			changes = append(changes, FieldChange{field, nil, toValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+// flattenJob returns the fields of a job as they appear in JSON
+func flattenJob(job *Job) (map[string]interface{}, error) {
+	js, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err = json.Unmarshal(js, &object); err != nil {
+		return nil, err
+	}
+	delete(object, "id")
+	delete(object, "revision")
+	delete(object, "paused")
+
+	fields := make(map[string]interface{})
+	flattenObject("", object, fields)
+	return fields, nil
+}
+
+func flattenObject(prefix string, object map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenObject(prefix+key+".", nested, fields)
+			continue
+		}
+		fields[prefix+key] = value
+	}
+}
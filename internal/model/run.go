@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type RunId int64
+
+var (
+	ErrorRunNotFound   = errors.New("run not found")
+	ErrorRunNotRunning = errors.New("run is not running")
+)
+
+// lostRunError is recorded as the error of lost runs
+const lostRunError = "the run was lost: no live scheduler finished it within its timeout and grace period"
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	// RunLost is recorded by the reaper for runs which were never finished, e.g. because their scheduler crashed
+	RunLost RunStatus = "lost"
+	// RunCancelled is recorded for runs whose process was terminated because their cancellation was requested
+	RunCancelled RunStatus = "cancelled"
+)
+
+// ReclaimedJob is a job released by the reaper because it was still marked running after its timeout and
+// grace period passed since it was claimed
+type ReclaimedJob struct {
+	Job       *Job
+	ClaimedAt time.Time
+	// LostRuns are the runs of the job which were still recorded as running and were marked lost
+	LostRuns []RunId
+}
+
+type TerminationCause string
+
+const (
+	TerminatedByTimeout  TerminationCause = "timeout"
+	TerminatedByCPULimit TerminationCause = "cpuLimit"
+)
+
+type Run struct {
+	Id           RunId            `json:"id"`
+	JobId        JobId            `json:"jobId"`
+	JobRevision  uint             `json:"jobRevision,omitempty"`
+	SchedulerId  SchedulerId      `json:"schedulerId,omitempty"`
+	Status       RunStatus        `json:"status"`
+	StartTime    time.Time        `json:"startTime"`
+	EndTime      *time.Time       `json:"endTime,omitempty"`
+	ExitCode     *int             `json:"exitCode,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	TerminatedBy TerminationCause `json:"terminatedBy,omitempty"`
+	Usage        *ResourceUsage   `json:"usage,omitempty"`
+	// OutputTail is the end of the combined stdout and stderr of the run's process
+	OutputTail string `json:"outputTail,omitempty"`
+	// CancelRequestedAt is the time the run's cancellation was requested
+	CancelRequestedAt *time.Time `json:"cancelRequestedAt,omitempty"`
+}
+
+// ResourceUsage is the resource usage of a finished run's process
+type ResourceUsage struct {
+	UserCPUSeconds   float64 `json:"userCpuSeconds"`
+	SystemCPUSeconds float64 `json:"systemCpuSeconds"`
+	MaxRSSBytes      int64   `json:"maxRssBytes"`
+}
+
+// JobStats are aggregate statistics of a job's finished runs
+type JobStats struct {
+	JobId              JobId    `json:"jobId"`
+	Runs               int64    `json:"runs"`
+	SucceededRuns      int64    `json:"succeededRuns"`
+	FailedRuns         int64    `json:"failedRuns"`
+	DurationP50Seconds *float64 `json:"durationP50Seconds,omitempty"`
+	DurationP95Seconds *float64 `json:"durationP95Seconds,omitempty"`
+	AverageCPUSeconds  *float64 `json:"averageCpuSeconds,omitempty"`
+	AverageMaxRSSBytes *float64 `json:"averageMaxRssBytes,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type SchedulerId int64
+
+const (
+	// SchedulerHeartbeatInterval is how often running schedulers record that they are alive
+	SchedulerHeartbeatInterval = 10 * time.Second
+	// schedulerDeadAfter is how long after its last heartbeat a scheduler which wasn't stopped is
+	// considered dead, e.g. because its process was killed
+	schedulerDeadAfter = 3 * SchedulerHeartbeatInterval
+)
+
+// SchedulerInstance is a scheduler registered by a running app
+type SchedulerInstance struct {
+	Id                  SchedulerId `json:"id"`
+	Hostname            string      `json:"hostname"`
+	PingIntervalSeconds float64     `json:"pingIntervalSeconds"`
+	Version             string      `json:"version"`
+	StartTime           time.Time   `json:"startTime"`
+	LastHeartbeat       time.Time   `json:"lastHeartbeat"`
+	StopTime            *time.Time  `json:"stopTime,omitempty"`
+	// Live is false for stopped schedulers and ones which missed their heartbeats
+	Live bool `json:"live"`
+}
+
+var ErrorSchedulerNotFound = errors.New("scheduler not found")
+
+type SchedulerStorage interface {
+	// RegisterScheduler records a started scheduler, setting its id, start time and first heartbeat
+	RegisterScheduler(ctx context.Context, instance *SchedulerInstance) error
+	// HeartbeatScheduler records that a scheduler is alive. It fails with ErrorSchedulerNotFound if the
+	// scheduler isn't registered or was stopped
+	HeartbeatScheduler(ctx context.Context, id SchedulerId) error
+	StopScheduler(ctx context.Context, id SchedulerId) error
+	// ListSchedulers returns registered schedulers, the most recently registered first
+	ListSchedulers(ctx context.Context, limit uint) ([]*SchedulerInstance, error)
+}
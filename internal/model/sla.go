@@ -0,0 +1,31 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// SLAViolation is a job which hasn't succeeded within its MaxSuccessInterval. Jobs which never succeeded are
+// measured from their creation
+type SLAViolation struct {
+	JobId              JobId  `json:"jobId"`
+	Namespace          string `json:"namespace"`
+	JobName            string `json:"jobName"`
+	MaxSuccessInterval uint   `json:"maxSuccessInterval"`
+	// LastSuccess is the end time of the job's last successful run
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// Since is when the interval ran out
+	Since time.Time `json:"since"`
+	// AlertedAt is when the violation was first detected by the checker, which alerts about it once
+	AlertedAt *time.Time `json:"alertedAt,omitempty"`
+}
+
+type SLAStorage interface {
+	// GetSLAViolations returns the jobs of the namespace which violate their SLA, ordered by job id. Paused jobs
+	// aren't expected to succeed and are left out
+	GetSLAViolations(ctx context.Context, namespace string) ([]*SLAViolation, error)
+	// MarkSLAViolations records the current violations of all namespaces and returns the ones which weren't
+	// recorded before, so each violation is alerted about once even with several checkers. Marks of jobs
+	// which no longer violate their SLA are cleared
+	MarkSLAViolations(ctx context.Context) ([]*SLAViolation, error)
+}
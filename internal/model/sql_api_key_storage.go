@@ -0,0 +1,129 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go-work/internal/auth"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+// uniqueViolation is the PostgreSQL error code of unique constraint violations
+const uniqueViolation = "23505"
+
+// apiKeyTouchInterval is how outdated the recorded last use of an API key may get
+const apiKeyTouchInterval = time.Minute
+
+func (st *sqlJobStorage) CreateAPIKey(
+	ctx context.Context,
+	name string,
+	role auth.Role,
+	namespaces []string,
+	keyHash []byte,
+) (*APIKey, error) {
+	key := APIKey{Name: name, Role: role, Namespaces: namespaces, CreatedAt: time.Now()}
+	if key.Namespaces == nil {
+		key.Namespaces = []string{}
+	}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			sqlquery.NewAPIKey,
+			name,
+			role,
+			pq.Array(key.Namespaces),
+			keyHash,
+			key.CreatedAt,
+		).Scan(&key.Id)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, ErrorAPIKeyExists
+		}
+		return nil, fmt.Errorf("failed creating API key %s: %w", name, err)
+	}
+	return &key, nil
+}
+
+func (st *sqlJobStorage) AuthenticateAPIKey(ctx context.Context, keyHash []byte) (*APIKey, error) {
+	key, err := st.getAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed authenticating API key: %w", err)
+	}
+	return key, nil
+}
+
+// RecordAPIKeyUse writes the last use of a key at most once per apiKeyTouchInterval, so authenticating
+// requests rarely waits for the storage lock
+func (st *sqlJobStorage) RecordAPIKeyUse(ctx context.Context, key *APIKey) error {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+	if err := st.updateJobs(ctx, sqlquery.TouchAPIKey, now, key.Id, now.Add(-apiKeyTouchInterval)); err != nil {
+		return fmt.Errorf("failed recording use of API key %s: %w", key.Name, err)
+	}
+	key.LastUsedAt = &now
+	return nil
+}
+
+func (st *sqlJobStorage) getAPIKeyByHash(ctx context.Context, keyHash []byte) (*APIKey, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	key := APIKey{}
+	if err := scanAPIKey(st.database.QueryRowContext(ctx, sqlquery.GetAPIKeyByHash, keyHash), &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (st *sqlJobStorage) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.ListAPIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*APIKey, 0)
+	for rows.Next() {
+		key := APIKey{}
+		if err = scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("failed scanning API key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (st *sqlJobStorage) DeleteAPIKey(ctx context.Context, name string) error {
+	st.rwLock.Lock()
+	defer st.rwLock.Unlock()
+
+	result, err := st.database.ExecContext(ctx, sqlquery.DeleteAPIKey, name)
+	if err != nil {
+		return fmt.Errorf("failed deleting API key %s: %w", name, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrorAPIKeyNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(sc scanner, key *APIKey) error {
+	return sc.Scan(&key.Id, &key.Name, &key.Role, pq.Array(&key.Namespaces), &key.CreatedAt, &key.LastUsedAt)
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-work/internal/auth"
+	"go-work/internal/model/sqlquery"
+	"go-work/internal/requestid"
+	"time"
+)
+
+// writeAuditEntry records a change of a job within the transaction making it, taking the
+// actor and request id from the context
+func writeAuditEntry(ctx context.Context, tx *sql.Tx, action AuditAction, before *Job, after *Job) error {
+	job := after
+	if job == nil {
+		job = before
+	}
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		sqlquery.NewAuditEntry,
+		time.Now(),
+		actorFromContext(ctx),
+		requestid.FromContext(ctx),
+		action,
+		job.Id,
+		job.Name,
+		beforeJSON,
+		afterJSON,
+	)
+	if err != nil {
+		return fmt.Errorf("failed writing audit entry: %w", err)
+	}
+	return nil
+}
+
+// actorFromContext returns the name of the authenticated client making a change
+func actorFromContext(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		return principal.Name
+	}
+	return SystemActor
+}
+
+// marshalSnapshot returns a job as a JSON string. It isn't returned as bytes, which the
+// driver would send as bytea rather than jsonb
+func marshalSnapshot(job *Job) (sql.NullString, error) {
+	if job == nil {
+		return sql.NullString{}, nil
+	}
+	snapshot, err := json.Marshal(job)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed marshalling job snapshot: %w", err)
+	}
+	return sql.NullString{String: string(snapshot), Valid: true}, nil
+}
+
+func (st *sqlJobStorage) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetAuditEntries, filter.JobId, filter.Actor, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		entry := &AuditEntry{}
+		var before, after []byte
+		err = rows.Scan(
+			&entry.Id,
+			&entry.Time,
+			&entry.Actor,
+			&entry.RequestId,
+			&entry.Action,
+			&entry.JobId,
+			&entry.JobName,
+			&before,
+			&after,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning audit entry: %w", err)
+		}
+		if entry.Before, err = unmarshalSnapshot(before); err != nil {
+			return nil, err
+		}
+		if entry.After, err = unmarshalSnapshot(after); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func unmarshalSnapshot(snapshot []byte) (*Job, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	job := &Job{}
+	if err := json.Unmarshal(snapshot, job); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling job snapshot: %w", err)
+	}
+	return job, nil
+}
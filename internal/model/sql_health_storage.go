@@ -0,0 +1,23 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"go-work/internal/model/sqlquery"
+)
+
+// CheckHealth doesn't take the storage lock, so it reports a database which is unreachable even
+// while other operations are stuck waiting for it
+func (st *sqlJobStorage) CheckHealth(ctx context.Context) error {
+	if err := st.database.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed checking database availability: %w", err)
+	}
+	var version int
+	if err := st.database.QueryRowContext(ctx, sqlquery.GetSchemaVersion).Scan(&version); err != nil {
+		return fmt.Errorf("failed getting schema version: %w", err)
+	}
+	if version != SchemaVersion {
+		return fmt.Errorf("database schema version is %d, expected %d", version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-work/internal/labels"
+	"strings"
+)
+
+// selectorCondition translates a label selector into an SQL condition on the labels column,
+// appending its parameters to params. Equality is expressed as containment, which uses the
+// GIN index on labels
+func selectorCondition(selector labels.Selector, params []any) (string, []any) {
+	if len(selector) == 0 {
+		return "TRUE", params
+	}
+	param := func(value any) string {
+		params = append(params, value)
+		return fmt.Sprintf("$%d", len(params))
+	}
+	contains := func(key string, values []string) string {
+		conditions := make([]string, 0, len(values))
+		for _, value := range values {
+			label, _ := json.Marshal(map[string]string{key: value})
+			conditions = append(conditions, "labels @> "+param(string(label))+"::jsonb")
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")"
+	}
+
+	conditions := make([]string, 0, len(selector))
+	for _, requirement := range selector {
+		var condition string
+		switch requirement.Operator {
+		case labels.Equals, labels.In:
+			condition = contains(requirement.Key, requirement.Values)
+		case labels.NotEquals, labels.NotIn:
+			condition = "NOT " + contains(requirement.Key, requirement.Values)
+		case labels.Exists:
+			condition = "labels ? " + param(requirement.Key)
+		case labels.DoesNotExist:
+			condition = "NOT labels ? " + param(requirement.Key)
+		}
+		conditions = append(conditions, condition)
+	}
+	return strings.Join(conditions, " AND "), params
+}
+
+// marshalLabels returns labels as a JSON string for the labels column
+func marshalLabels(jobLabels map[string]string) (string, error) {
+	if jobLabels == nil {
+		return "{}", nil
+	}
+	js, err := json.Marshal(jobLabels)
+	if err != nil {
+		return "", fmt.Errorf("failed marshalling labels: %w", err)
+	}
+	return string(js), nil
+}
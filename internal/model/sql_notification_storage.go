@@ -0,0 +1,234 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+func (st *sqlJobStorage) CreateNotificationRule(ctx context.Context, rule *NotificationRule) error {
+	rule.CreatedAt = time.Now()
+	webhookURL, webhookFormat, webhookSecret := "", WebhookJSON, ""
+	if rule.Webhook != nil {
+		webhookURL, webhookFormat, webhookSecret = rule.Webhook.URL, rule.Webhook.Format, rule.Webhook.Secret
+	}
+	emailTo := []string{}
+	if rule.Email != nil {
+		emailTo = rule.Email.To
+	}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			sqlquery.NewNotificationRule,
+			rule.Namespace,
+			sql.NullInt64{Int64: int64(rule.JobId), Valid: rule.JobId != 0},
+			pq.Array(rule.Events),
+			rule.ConsecutiveFailures,
+			webhookURL,
+			webhookFormat,
+			webhookSecret,
+			pq.Array(emailTo),
+			rule.CreatedAt,
+		).Scan(&rule.Id)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return fmt.Errorf("failed creating notification rule in namespace %s: %w", rule.Namespace, err)
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) GetNotificationRule(
+	ctx context.Context,
+	namespace string,
+	id NotificationRuleId,
+) (*NotificationRule, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rule := NotificationRule{}
+	err := scanNotificationRule(st.database.QueryRowContext(ctx, sqlquery.GetNotificationRule, namespace, id), &rule)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotificationRuleNotFound
+		}
+		return nil, fmt.Errorf("failed getting notification rule with id %d: %w", id, err)
+	}
+	return &rule, nil
+}
+
+func (st *sqlJobStorage) ListNotificationRules(ctx context.Context, namespace string) ([]*NotificationRule, error) {
+	return st.queryNotificationRules(ctx, sqlquery.ListNotificationRules, namespace)
+}
+
+func (st *sqlJobStorage) GetJobNotificationRules(ctx context.Context, job *Job) ([]*NotificationRule, error) {
+	return st.queryNotificationRules(ctx, sqlquery.GetJobNotificationRules, job.Namespace, job.Id)
+}
+
+func (st *sqlJobStorage) queryNotificationRules(
+	ctx context.Context,
+	query string,
+	params ...any,
+) ([]*NotificationRule, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting notification rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]*NotificationRule, 0)
+	for rows.Next() {
+		rule := NotificationRule{}
+		if err = scanNotificationRule(rows, &rule); err != nil {
+			return nil, fmt.Errorf("failed scanning notification rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting notification rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (st *sqlJobStorage) DeleteNotificationRule(ctx context.Context, namespace string, id NotificationRuleId) error {
+	st.rwLock.Lock()
+	defer st.rwLock.Unlock()
+
+	result, err := st.database.ExecContext(ctx, sqlquery.DeleteNotificationRule, namespace, id)
+	if err != nil {
+		return fmt.Errorf("failed deleting notification rule with id %d: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrorNotificationRuleNotFound
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) CreateNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error {
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			sqlquery.NewNotificationDelivery,
+			delivery.RuleId,
+			sql.NullInt64{Int64: int64(delivery.JobId), Valid: delivery.JobId != 0},
+			sql.NullInt64{Int64: int64(delivery.RunId), Valid: delivery.RunId != 0},
+			pq.Array(delivery.Events),
+			delivery.Status,
+			delivery.CreatedAt,
+		).Scan(&delivery.Id)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return fmt.Errorf("failed creating delivery of notification rule with id %d: %w", delivery.RuleId, err)
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) UpdateNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error {
+	err := st.updateJobs(
+		ctx,
+		sqlquery.UpdateNotificationDelivery,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseCode,
+		delivery.Error,
+		delivery.FinishedAt,
+		delivery.Id,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed updating notification delivery with id %d: %w", delivery.Id, err)
+	}
+	return err
+}
+
+func (st *sqlJobStorage) GetNotificationDeliveries(
+	ctx context.Context,
+	ruleId NotificationRuleId,
+	limit uint,
+) ([]*NotificationDelivery, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetNotificationDeliveries, ruleId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting deliveries of notification rule with id %d: %w", ruleId, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*NotificationDelivery, 0)
+	for rows.Next() {
+		delivery := NotificationDelivery{}
+		var jobId, runId sql.NullInt64
+		var events []string
+		err = rows.Scan(
+			&delivery.Id,
+			&delivery.RuleId,
+			&jobId,
+			&runId,
+			pq.Array(&events),
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.ResponseCode,
+			&delivery.Error,
+			&delivery.CreatedAt,
+			&delivery.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning notification delivery: %w", err)
+		}
+		delivery.JobId = JobId(jobId.Int64)
+		delivery.RunId = RunId(runId.Int64)
+		delivery.Events = toNotificationEvents(events)
+		deliveries = append(deliveries, &delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting deliveries of notification rule with id %d: %w", ruleId, err)
+	}
+	return deliveries, nil
+}
+
+func scanNotificationRule(sc scanner, rule *NotificationRule) error {
+	var jobId sql.NullInt64
+	var events []string
+	var webhookURL, webhookFormat, webhookSecret string
+	var emailTo []string
+	err := sc.Scan(
+		&rule.Id,
+		&rule.Namespace,
+		&jobId,
+		pq.Array(&events),
+		&rule.ConsecutiveFailures,
+		&webhookURL,
+		&webhookFormat,
+		&webhookSecret,
+		pq.Array(&emailTo),
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	rule.JobId = JobId(jobId.Int64)
+	rule.Events = toNotificationEvents(events)
+	if webhookURL != "" {
+		rule.Webhook = &Webhook{URL: webhookURL, Format: WebhookFormat(webhookFormat), Secret: webhookSecret}
+	}
+	if len(emailTo) > 0 {
+		rule.Email = &Email{To: emailTo}
+	}
+	return nil
+}
+
+func toNotificationEvents(values []string) []NotificationEvent {
+	events := make([]NotificationEvent, len(values))
+	for i, value := range values {
+		events[i] = NotificationEvent(value)
+	}
+	return events
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+// quotaWindow is the period over which the timeouts of claimed jobs are limited by Quota.MaxTimeoutPerHour
+const quotaWindow = time.Hour
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (st *sqlJobStorage) GetQuotaUsage(ctx context.Context, namespace string) (*QuotaUsage, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	usage := QuotaUsage{Namespace: namespace}
+	err := scanQuota(st.database.QueryRowContext(ctx, sqlquery.GetQuota, namespace), &usage.Namespace, &usage.Quota)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed getting quota of namespace %s: %w", namespace, err)
+	}
+	if err = st.database.QueryRowContext(ctx, sqlquery.CountJobs, namespace).Scan(&usage.Jobs); err != nil {
+		return nil, fmt.Errorf("failed counting jobs of namespace %s: %w", namespace, err)
+	}
+	if err = scanRunUsage(ctx, st.database, &usage, time.Now()); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func (st *sqlJobStorage) SetQuota(ctx context.Context, namespace string, quota Quota) error {
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if quota == (Quota{}) {
+			_, err = tx.ExecContext(ctx, sqlquery.DeleteQuota, namespace)
+		} else {
+			_, err = tx.ExecContext(
+				ctx,
+				sqlquery.SetQuota,
+				namespace,
+				quota.MaxJobs,
+				quota.MaxConcurrentRuns,
+				quota.MaxTimeoutPerHour,
+			)
+		}
+		return err
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return fmt.Errorf("failed setting quota of namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// lockQuotas returns the quotas of the namespaces which have one, locking them until the end of the transaction
+func lockQuotas(ctx context.Context, tx *sql.Tx, namespaces []string) (map[string]Quota, error) {
+	rows, err := tx.QueryContext(ctx, sqlquery.GetQuotasForUpdate, pq.Array(namespaces))
+	if err != nil {
+		return nil, fmt.Errorf("failed locking quotas: %w", err)
+	}
+	defer rows.Close()
+
+	quotas := make(map[string]Quota)
+	for rows.Next() {
+		var namespace string
+		var quota Quota
+		if err = scanQuota(rows, &namespace, &quota); err != nil {
+			return nil, fmt.Errorf("failed scanning quota: %w", err)
+		}
+		quotas[namespace] = quota
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return quotas, rows.Close()
+}
+
+// checkJobQuota checks that the quota of the namespace allows a job with the given timeout. Jobs which are
+// added to the namespace also count against Quota.MaxJobs
+func checkJobQuota(ctx context.Context, tx *sql.Tx, namespace string, timeout uint, added bool) error {
+	quotas, err := lockQuotas(ctx, tx, []string{namespace})
+	if err != nil {
+		return err
+	}
+	quota, ok := quotas[namespace]
+	if !ok {
+		return nil
+	}
+	// Runs of such a job would never fit into the hourly limit
+	if quota.MaxTimeoutPerHour != 0 && timeout > quota.MaxTimeoutPerHour {
+		return fmt.Errorf(
+			"timeout of %d seconds exceeds the limit of %d seconds per hour: %w",
+			timeout,
+			quota.MaxTimeoutPerHour,
+			ErrorQuotaExceeded,
+		)
+	}
+	if !added || quota.MaxJobs == 0 {
+		return nil
+	}
+	var jobs uint
+	if err = tx.QueryRowContext(ctx, sqlquery.CountJobs, namespace).Scan(&jobs); err != nil {
+		return fmt.Errorf("failed counting jobs of namespace %s: %w", namespace, err)
+	}
+	if jobs >= quota.MaxJobs {
+		return fmt.Errorf("namespace %s has %d jobs, the limit is %d: %w", namespace, jobs, quota.MaxJobs, ErrorQuotaExceeded)
+	}
+	return nil
+}
+
+// admitJobs returns the due jobs which can start without exceeding the quotas of their namespaces, in the
+// order they are due. The rest stay due until the runs of their namespaces free up
+func admitJobs(ctx context.Context, tx *sql.Tx, due []*Job, now time.Time) ([]*Job, error) {
+	namespaces := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, job := range due {
+		if !seen[job.Namespace] {
+			seen[job.Namespace] = true
+			namespaces = append(namespaces, job.Namespace)
+		}
+	}
+	quotas, err := lockQuotas(ctx, tx, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make(map[string]*QuotaUsage)
+	admitted := make([]*Job, 0, len(due))
+	for _, job := range due {
+		quota, ok := quotas[job.Namespace]
+		if !ok || (quota.MaxConcurrentRuns == 0 && quota.MaxTimeoutPerHour == 0) {
+			admitted = append(admitted, job)
+			continue
+		}
+		usage, ok := usages[job.Namespace]
+		if !ok {
+			usage = &QuotaUsage{Namespace: job.Namespace}
+			if err = scanRunUsage(ctx, tx, usage, now); err != nil {
+				return nil, err
+			}
+			usages[job.Namespace] = usage
+		}
+		if quota.MaxConcurrentRuns != 0 && usage.RunningJobs >= quota.MaxConcurrentRuns {
+			continue
+		}
+		if quota.MaxTimeoutPerHour != 0 && usage.TimeoutLastHour+job.Timeout > quota.MaxTimeoutPerHour {
+			continue
+		}
+		usage.RunningJobs++
+		usage.TimeoutLastHour += job.Timeout
+		admitted = append(admitted, job)
+	}
+	return admitted, nil
+}
+
+// chargeQuotas charges the timeouts of the claimed jobs to their namespaces. It runs in the transaction which
+// marks them running, so claims by other schedulers see the charges before the runs even start
+func chargeQuotas(ctx context.Context, tx *sql.Tx, ids []int64, now time.Time) error {
+	if _, err := tx.ExecContext(ctx, sqlquery.DeleteOldCharges, now.Add(-quotaWindow)); err != nil {
+		return fmt.Errorf("failed deleting old quota charges: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlquery.ChargeQuotas, pq.Array(ids), now); err != nil {
+		return fmt.Errorf("failed charging quotas: %w", err)
+	}
+	return nil
+}
+
+// scanRunUsage fills in the running jobs and the timeouts charged within quotaWindow before now
+func scanRunUsage(ctx context.Context, querier rowQuerier, usage *QuotaUsage, now time.Time) error {
+	err := querier.QueryRowContext(ctx, sqlquery.CountRunningJobs, usage.Namespace).Scan(&usage.RunningJobs)
+	if err != nil {
+		return fmt.Errorf("failed counting running jobs of namespace %s: %w", usage.Namespace, err)
+	}
+	err = querier.QueryRowContext(ctx, sqlquery.SumChargedTimeouts, usage.Namespace, now.Add(-quotaWindow)).
+		Scan(&usage.TimeoutLastHour)
+	if err != nil {
+		return fmt.Errorf("failed summing charged timeouts of namespace %s: %w", usage.Namespace, err)
+	}
+	return nil
+}
+
+func scanQuota(sc scanner, namespace *string, quota *Quota) error {
+	return sc.Scan(namespace, &quota.MaxJobs, &quota.MaxConcurrentRuns, &quota.MaxTimeoutPerHour)
+}
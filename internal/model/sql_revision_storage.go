@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+// writeRevision records the current definition of a job within the transaction changing it
+func writeRevision(ctx context.Context, tx *sql.Tx, job *Job) error {
+	definition, err := marshalSnapshot(job)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		sqlquery.NewJobRevision,
+		job.Id,
+		job.Revision,
+		time.Now(),
+		actorFromContext(ctx),
+		definition,
+	)
+	if err != nil {
+		return fmt.Errorf("failed writing job revision: %w", err)
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) GetJobRevisions(ctx context.Context, id JobId) ([]*JobRevision, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetJobRevisions, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting revisions of job with id %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]*JobRevision, 0)
+	for rows.Next() {
+		revision := &JobRevision{}
+		if err = scanRevision(rows, revision); err != nil {
+			return nil, fmt.Errorf("failed scanning revision of job with id %d: %w", id, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting revisions of job with id %d: %w", id, err)
+	}
+	return revisions, nil
+}
+
+func (st *sqlJobStorage) GetJobRevision(ctx context.Context, id JobId, revision uint) (*JobRevision, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	jobRevision := &JobRevision{}
+	err := scanRevision(st.database.QueryRowContext(ctx, sqlquery.GetJobRevision, id, revision), jobRevision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed getting revision %d of job with id %d: %w", revision, id, err)
+	}
+	return jobRevision, nil
+}
+
+func scanRevision(sc scanner, revision *JobRevision) error {
+	var definition []byte
+	err := sc.Scan(&revision.JobId, &revision.Revision, &revision.CreatedAt, &revision.Actor, &definition)
+	if err != nil {
+		return err
+	}
+	revision.Job, err = unmarshalSnapshot(definition)
+	return err
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+func (st *sqlJobStorage) RegisterScheduler(ctx context.Context, instance *SchedulerInstance) error {
+	now := time.Now()
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			sqlquery.RegisterScheduler,
+			instance.Hostname,
+			instance.PingIntervalSeconds,
+			instance.Version,
+			now,
+		).Scan(&instance.Id)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return fmt.Errorf("failed registering scheduler on %s: %w", instance.Hostname, err)
+	}
+	instance.StartTime = now
+	instance.LastHeartbeat = now
+	instance.Live = true
+	return nil
+}
+
+func (st *sqlJobStorage) HeartbeatScheduler(ctx context.Context, id SchedulerId) error {
+	return st.updateScheduler(ctx, sqlquery.HeartbeatScheduler, id)
+}
+
+func (st *sqlJobStorage) StopScheduler(ctx context.Context, id SchedulerId) error {
+	return st.updateScheduler(ctx, sqlquery.StopScheduler, id)
+}
+
+// updateScheduler runs a query which sets a time on a running scheduler
+func (st *sqlJobStorage) updateScheduler(ctx context.Context, query string, id SchedulerId) error {
+	st.rwLock.Lock()
+	defer st.rwLock.Unlock()
+
+	result, err := st.database.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed updating scheduler with id %d: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrorSchedulerNotFound
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) ListSchedulers(ctx context.Context, limit uint) ([]*SchedulerInstance, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.ListSchedulers, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting schedulers: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	instances := make([]*SchedulerInstance, 0)
+	for rows.Next() {
+		instance := &SchedulerInstance{}
+		err = rows.Scan(
+			&instance.Id,
+			&instance.Hostname,
+			&instance.PingIntervalSeconds,
+			&instance.Version,
+			&instance.StartTime,
+			&instance.LastHeartbeat,
+			&instance.StopTime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning scheduler: %w", err)
+		}
+		instance.Live = instance.StopTime == nil && now.Sub(instance.LastHeartbeat) <= schedulerDeadAfter
+		instances = append(instances, instance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting schedulers: %w", err)
+	}
+	return instances, nil
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"go-work/internal/model/sqlquery"
+	"time"
+)
+
+func (st *sqlJobStorage) GetSLAViolations(ctx context.Context, namespace string) ([]*SLAViolation, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetSLAViolations, time.Now(), namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting SLA violations of namespace %s: %w", namespace, err)
+	}
+	violations, err := scanSLAViolations(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting SLA violations of namespace %s: %w", namespace, err)
+	}
+	return violations, nil
+}
+
+func (st *sqlJobStorage) MarkSLAViolations(ctx context.Context) ([]*SLAViolation, error) {
+	var unmarked []*SLAViolation
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, sqlquery.GetAllSLAViolations, now)
+		if err != nil {
+			return err
+		}
+		violations, err := scanSLAViolations(rows)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, len(violations))
+		byJob := make(map[JobId]*SLAViolation, len(violations))
+		for i, violation := range violations {
+			ids[i] = int64(violation.JobId)
+			byJob[violation.JobId] = violation
+		}
+		if _, err = tx.ExecContext(ctx, sqlquery.ClearSLAViolations, pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed clearing resolved violations: %w", err)
+		}
+
+		// Concurrent checkers wait for each other's row locks, so only one of them marks each violation
+		rows, err = tx.QueryContext(ctx, sqlquery.MarkSLAViolations, now, pq.Array(ids))
+		if err != nil {
+			return fmt.Errorf("failed marking violations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id JobId
+			if err = rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed scanning marked job id: %w", err)
+			}
+			violation := byJob[id]
+			violation.AlertedAt = &now
+			unmarked = append(unmarked, violation)
+		}
+		return rows.Err()
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed marking SLA violations: %w", err)
+	}
+	return unmarked, nil
+}
+
+func scanSLAViolations(rows *sql.Rows) ([]*SLAViolation, error) {
+	defer rows.Close()
+	violations := make([]*SLAViolation, 0)
+	for rows.Next() {
+		violation := &SLAViolation{}
+		err := rows.Scan(
+			&violation.JobId,
+			&violation.Namespace,
+			&violation.JobName,
+			&violation.MaxSuccessInterval,
+			&violation.LastSuccess,
+			&violation.Since,
+			&violation.AlertedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed scanning SLA violation: %w", err)
+		}
+		violations = append(violations, violation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
@@ -3,10 +3,12 @@ package model
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"github.com/robfig/cron/v3"
+	"go-work/internal/labels"
 	"go-work/internal/model/sqlquery"
 	"sync"
 	"time"
@@ -18,6 +20,30 @@ type sqlJobStorage struct {
 }
 
 func NewSQLJobStorage(ctx context.Context, driverName, dataSourceName string) (*sqlJobStorage, error) {
+	storage, err := openSQLStorage(ctx, driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = storage.init(ctx); err != nil {
+		storage.database.Close()
+		return nil, fmt.Errorf("failed initializing storage: %w", err)
+	}
+	return storage, nil
+}
+
+// NewSQLAPIKeyStorage opens the storage for managing API keys only. Unlike NewSQLJobStorage,
+// it doesn't schedule jobs without a next execution time
+func NewSQLAPIKeyStorage(ctx context.Context, driverName, dataSourceName string) (*sqlJobStorage, error) {
+	return openSQLStorage(ctx, driverName, dataSourceName)
+}
+
+// Close closes the database of the storage
+func (st *sqlJobStorage) Close() error {
+	return st.database.Close()
+}
+
+func openSQLStorage(ctx context.Context, driverName, dataSourceName string) (*sqlJobStorage, error) {
 	database, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed opening database: %w", err)
@@ -27,37 +53,61 @@ func NewSQLJobStorage(ctx context.Context, driverName, dataSourceName string) (*
 		database.Close()
 		return nil, fmt.Errorf("failed checking database availibility: %w", err)
 	}
+	return &sqlJobStorage{database, &sync.RWMutex{}}, nil
+}
 
-	storage := sqlJobStorage{database, &sync.RWMutex{}}
-	if err = storage.init(ctx); err != nil {
-		database.Close()
-		return nil, fmt.Errorf("failed initializing storage: %w", err)
+func (st *sqlJobStorage) CreateJob(ctx context.Context, job *Job) (JobId, error) {
+	schedule, err := cron.ParseStandard(job.CrontabString)
+	if err != nil {
+		return 0, fmt.Errorf("failed parsing crontab string \"%s\" while creating job: %w", job.CrontabString, err)
 	}
-	return &storage, nil
-}
 
-func (st *sqlJobStorage) CreateJob(ctx context.Context, name, crontabString, command string, arguments []string, timeout uint) (JobId, error) {
-	schedule, err := cron.ParseStandard(crontabString)
+	jobLabels, err := marshalLabels(job.Labels)
 	if err != nil {
-		return 0, fmt.Errorf("failed parsing crontab string \"%s\" while creating job: %w", crontabString, err)
+		return 0, err
+	}
+	namespace := job.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
 	}
 
 	var id JobId
+	var revision uint
 	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkJobQuota(ctx, tx, namespace, job.Timeout, true); err != nil {
+			return err
+		}
 		err := tx.QueryRowContext(
 			ctx,
 			sqlquery.NewJob,
-			name,
-			crontabString,
-			command,
-			pq.Array(arguments),
-			timeout,
+			job.Name,
+			job.CrontabString,
+			job.Mode,
+			job.Command,
+			job.Script,
+			job.Interpreter,
+			pq.Array(job.Arguments),
+			job.Timeout,
+			job.RunAsUser,
+			job.RunAsGroup,
+			job.Limits.AddressSpace,
+			job.Limits.CPUSeconds,
+			job.Limits.OpenFiles,
+			job.Limits.Processes,
 			schedule.Next(time.Now()),
-		).Scan(&id)
+			jobLabels,
+			namespace,
+			job.MaxSuccessInterval,
+		).Scan(&id, &revision)
 		if err != nil {
-			err = fmt.Errorf("failed scanning job id: %w", err)
+			return fmt.Errorf("failed scanning job id: %w", err)
 		}
-		return err
+		created := *job
+		created.Id, created.Namespace, created.Revision = id, namespace, revision
+		if err = writeRevision(ctx, tx, &created); err != nil {
+			return err
+		}
+		return writeAuditEntry(ctx, tx, JobCreated, nil, &created)
 	}
 
 	if err = st.transact(ctx, transactionFunc); err != nil {
@@ -76,39 +126,353 @@ func (st *sqlJobStorage) GetJob(ctx context.Context, id JobId) (*Job, error) {
 }
 
 func (st *sqlJobStorage) DeleteJob(ctx context.Context, id JobId) error {
-	err := st.updateJobs(ctx, sqlquery.DeleteJob, id)
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		deleted := Job{}
+		if err := scanJob(tx.QueryRowContext(ctx, sqlquery.DeleteJob, id, time.Now()), &deleted); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrorNotFound
+			}
+			return fmt.Errorf("failed scanning deleted job: %w", err)
+		}
+		return writeAuditEntry(ctx, tx, JobDeleted, &deleted, nil)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return fmt.Errorf("failed deleting job with id %d: %w", id, err)
+	}
+	return nil
+}
+
+func (st *sqlJobStorage) UpdateJob(ctx context.Context, job *Job) (*Job, error) {
+	var updated *Job
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) (err error) {
+		updated, err = changeDefinition(ctx, tx, job, JobUpdated)
+		return err
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed updating job with id %d: %w", job.Id, err)
+	}
+	return updated, nil
+}
+
+func (st *sqlJobStorage) RollbackJob(ctx context.Context, id JobId, revision uint) (*Job, error) {
+	var rolledBack *Job
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		jobRevision := JobRevision{}
+		if err := scanRevision(tx.QueryRowContext(ctx, sqlquery.GetJobRevision, id, revision), &jobRevision); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrorRevisionNotFound
+			}
+			return fmt.Errorf("failed scanning revision %d: %w", revision, err)
+		}
+		var err error
+		rolledBack, err = changeDefinition(ctx, tx, jobRevision.Job, JobRolledBack)
+		return err
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed rolling back job with id %d to revision %d: %w", id, revision, err)
+	}
+	return rolledBack, nil
+}
+
+// changeDefinition replaces the definition of the job with job.Id within a transaction,
+// recording the new revision and the change in the audit log
+func changeDefinition(ctx context.Context, tx *sql.Tx, job *Job, action AuditAction) (*Job, error) {
+	schedule, err := cron.ParseStandard(job.CrontabString)
 	if err != nil {
-		err = fmt.Errorf("failed deleting job with id %d: %w", id, err)
+		return nil, fmt.Errorf("failed parsing crontab string \"%s\": %w", job.CrontabString, err)
 	}
-	return err
+	jobLabels, err := marshalLabels(job.Labels)
+	if err != nil {
+		return nil, err
+	}
+	before := Job{}
+	if err = scanJob(tx.QueryRowContext(ctx, sqlquery.GetJobForUpdate, job.Id), &before); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, fmt.Errorf("failed scanning job: %w", err)
+	}
+	if err = checkJobQuota(ctx, tx, before.Namespace, job.Timeout, false); err != nil {
+		return nil, err
+	}
+
+	after := *job
+	after.Namespace = before.Namespace
+	after.Paused = before.Paused
+	err = tx.QueryRowContext(
+		ctx,
+		sqlquery.UpdateJob,
+		job.Id,
+		job.Name,
+		job.CrontabString,
+		job.Mode,
+		job.Command,
+		job.Script,
+		job.Interpreter,
+		pq.Array(job.Arguments),
+		job.Timeout,
+		job.RunAsUser,
+		job.RunAsGroup,
+		job.Limits.AddressSpace,
+		job.Limits.CPUSeconds,
+		job.Limits.OpenFiles,
+		job.Limits.Processes,
+		schedule.Next(time.Now()),
+		jobLabels,
+		job.MaxSuccessInterval,
+	).Scan(&after.Revision)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, ErrorJobExists
+		}
+		return nil, fmt.Errorf("failed scanning job revision: %w", err)
+	}
+
+	if err = writeRevision(ctx, tx, &after); err != nil {
+		return nil, err
+	}
+	if err = writeAuditEntry(ctx, tx, action, &before, &after); err != nil {
+		return nil, err
+	}
+	return &after, nil
 }
 
-func (st *sqlJobStorage) GetJobByName(ctx context.Context, name string) (*Job, error) {
-	job, err := st.getJobBy(ctx, sqlquery.GetJobByName, name)
+func (st *sqlJobStorage) RestoreJob(ctx context.Context, id JobId) (*Job, error) {
+	restored := Job{}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		if err := scanJob(tx.QueryRowContext(ctx, sqlquery.GetDeletedJob, id), &restored); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrorNotFound
+			}
+			return fmt.Errorf("failed scanning deleted job: %w", err)
+		}
+		schedule, err := cron.ParseStandard(restored.CrontabString)
+		if err != nil {
+			return fmt.Errorf("failed parsing crontab string \"%s\": %w", restored.CrontabString, err)
+		}
+		if err = checkJobQuota(ctx, tx, restored.Namespace, restored.Timeout, true); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, sqlquery.RestoreJob, id, schedule.Next(time.Now())); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return ErrorJobExists
+			}
+			return err
+		}
+		return writeAuditEntry(ctx, tx, JobRestored, nil, &restored)
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed restoring job with id %d: %w", id, err)
+	}
+	return &restored, nil
+}
+
+func (st *sqlJobStorage) PurgeDeletedJobs(ctx context.Context, deletedBefore time.Time) (int, error) {
+	purged := 0
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, sqlquery.PurgeDeletedJobs, deletedBefore)
+		if err != nil {
+			return err
+		}
+		jobs := make([]Job, 0)
+		for rows.Next() {
+			job := Job{}
+			if err = scanJob(rows, &job); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed scanning purged job: %w", err)
+			}
+			jobs = append(jobs, job)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range jobs {
+			if err = writeAuditEntry(ctx, tx, JobPurged, &jobs[i], nil); err != nil {
+				return err
+			}
+		}
+		purged = len(jobs)
+		return nil
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return 0, fmt.Errorf("failed purging deleted jobs: %w", err)
+	}
+	return purged, nil
+}
+
+func (st *sqlJobStorage) GetJobByName(ctx context.Context, namespace string, name string) (*Job, error) {
+	job, err := st.getJobBy(ctx, sqlquery.GetJobByName, namespace, name)
 	if err != nil {
-		err = fmt.Errorf("failed getting job by name %s: %w", name, err)
+		err = fmt.Errorf("failed getting job by name %s in namespace %s: %w", name, namespace, err)
 	}
 	return &job, err
 }
 
+func (st *sqlJobStorage) GetJobNamespace(ctx context.Context, id JobId) (string, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	var namespace string
+	if err := st.database.QueryRowContext(ctx, sqlquery.GetJobNamespace, id).Scan(&namespace); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrorNotFound
+		}
+		return "", fmt.Errorf("failed getting namespace of job with id %d: %w", id, err)
+	}
+	return namespace, nil
+}
+
+func (st *sqlJobStorage) ListJobs(ctx context.Context, namespace string, selector labels.Selector) ([]*Job, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	condition, params := selectorCondition(selector, []any{namespace})
+	rows, err := st.database.QueryContext(ctx, fmt.Sprintf(sqlquery.ListJobs, condition), params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing jobs: %w", err)
+	}
+	jobs, err := collectJobs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func (st *sqlJobStorage) PauseJobs(ctx context.Context, namespace string, selector labels.Selector, paused bool) ([]*Job, error) {
+	action := JobPaused
+	if !paused {
+		action = JobResumed
+	}
+
+	var changed []*Job
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		condition, params := selectorCondition(selector, []any{namespace, paused})
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(sqlquery.PauseJobs, condition), params...)
+		if err != nil {
+			return err
+		}
+		if changed, err = collectJobs(rows); err != nil {
+			return err
+		}
+
+		for _, job := range changed {
+			if !paused {
+				// Runs missed while the job was paused are skipped
+				schedule, err := cron.ParseStandard(job.CrontabString)
+				if err != nil {
+					return fmt.Errorf("failed parsing crontab string \"%s\": %w", job.CrontabString, err)
+				}
+				if _, err = tx.ExecContext(ctx, sqlquery.SetNextExecutionTime, schedule.Next(time.Now()), job.Id); err != nil {
+					return fmt.Errorf("failed scheduling resumed job: %w", err)
+				}
+			}
+			before := *job
+			before.Paused = !paused
+			if err = writeAuditEntry(ctx, tx, action, &before, job); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed to %s jobs: %w", action, err)
+	}
+	return changed, nil
+}
+
+func (st *sqlJobStorage) DeleteJobs(ctx context.Context, namespace string, selector labels.Selector) ([]*Job, error) {
+	var deleted []*Job
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		condition, params := selectorCondition(selector, []any{namespace, time.Now()})
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(sqlquery.DeleteJobs, condition), params...)
+		if err != nil {
+			return err
+		}
+		if deleted, err = collectJobs(rows); err != nil {
+			return err
+		}
+		for _, job := range deleted {
+			if err = writeAuditEntry(ctx, tx, JobDeleted, job, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed deleting jobs: %w", err)
+	}
+	return deleted, nil
+}
+
+// collectJobs scans and closes rows of jobs
+func collectJobs(rows *sql.Rows) ([]*Job, error) {
+	defer rows.Close()
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		job := Job{}
+		if err := scanJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed scanning job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, rows.Close()
+}
+
+// MarkDueJobsRunning claims the due jobs which the quotas of their namespaces allow to start
 func (st *sqlJobStorage) MarkDueJobsRunning(ctx context.Context) ([]*Job, error) {
 	jobs := make([]*Job, 0)
 	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
-		rows, err := tx.QueryContext(ctx, sqlquery.MarkDueJobsRunning, time.Now())
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, sqlquery.GetDueJobs, now)
 		if err != nil {
-			return fmt.Errorf("failed mark due jobs running query: %w", err)
+			return fmt.Errorf("failed get due jobs query: %w", err)
 		}
-		defer rows.Close()
-
+		due := make([]*Job, 0)
 		for rows.Next() {
 			job := Job{}
-			err := scanJob(rows, &job)
-			if err != nil {
+			if err = scanJob(trailingScanner{rows, []any{&job.DueTime}}, &job); err != nil {
+				rows.Close()
 				return fmt.Errorf("failed scanning job: %w", err)
 			}
-			jobs = append(jobs, &job)
+			due = append(due, &job)
 		}
-		return rows.Close()
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if jobs, err = admitJobs(ctx, tx, due, now); err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(jobs))
+		for i, job := range jobs {
+			ids[i] = int64(job.Id)
+		}
+		if _, err = tx.ExecContext(ctx, sqlquery.MarkJobsRunning, pq.Array(ids), now); err != nil {
+			return fmt.Errorf("failed mark jobs running query: %w", err)
+		}
+		return chargeQuotas(ctx, tx, ids, now)
 	}
 
 	if err := st.transact(ctx, transactionFunc); err != nil {
@@ -130,19 +494,355 @@ func (st *sqlJobStorage) MarkJobDone(ctx context.Context, job *Job) error {
 	return err
 }
 
+func (st *sqlJobStorage) TriggerJob(ctx context.Context, id JobId) (*Job, error) {
+	triggered := Job{}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		err := scanJob(tx.QueryRowContext(ctx, sqlquery.TriggerJob, id, time.Now()), &triggered)
+		if err == nil {
+			// Triggering doesn't change the job's definition, so it's recorded before and after
+			return writeAuditEntry(ctx, tx, JobTriggered, &triggered, &triggered)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		var running, paused bool
+		if err = tx.QueryRowContext(ctx, sqlquery.GetJobState, id).Scan(&running, &paused); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrorNotFound
+			}
+			return err
+		}
+		if running {
+			return ErrorJobRunning
+		}
+		return ErrorJobPaused
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed triggering job with id %d: %w", id, err)
+	}
+	return &triggered, nil
+}
+
+func (st *sqlJobStorage) ReclaimJobs(ctx context.Context, grace time.Duration) ([]*ReclaimedJob, error) {
+	reclaimed := make([]*ReclaimedJob, 0)
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, sqlquery.GetStuckJobs, now, int64(grace/time.Second))
+		if err != nil {
+			return fmt.Errorf("failed get stuck jobs query: %w", err)
+		}
+		stuck := make([]*ReclaimedJob, 0)
+		for rows.Next() {
+			reclaim := ReclaimedJob{Job: &Job{}}
+			if err = scanJob(trailingScanner{rows, []any{&reclaim.ClaimedAt}}, reclaim.Job); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed scanning job: %w", err)
+			}
+			stuck = append(stuck, &reclaim)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, reclaim := range stuck {
+			job := reclaim.Job
+			var liveRuns int
+			err = tx.QueryRowContext(ctx, sqlquery.CountLiveRuns, job.Id, now.Add(-schedulerDeadAfter)).Scan(&liveRuns)
+			if err != nil {
+				return fmt.Errorf("failed counting live runs of job with id %d: %w", job.Id, err)
+			}
+			if liveRuns > 0 {
+				continue
+			}
+			if reclaim.LostRuns, err = markRunsLost(ctx, tx, job.Id, now); err != nil {
+				return err
+			}
+			schedule, err := cron.ParseStandard(job.CrontabString)
+			if err != nil {
+				return fmt.Errorf("failed parsing crontab string %s while reclaiming job: %w", job.CrontabString, err)
+			}
+			if _, err = tx.ExecContext(ctx, sqlquery.MarkDone, schedule.Next(now), job.Id); err != nil {
+				return fmt.Errorf("failed releasing job with id %d: %w", job.Id, err)
+			}
+			reclaimed = append(reclaimed, reclaim)
+		}
+		return nil
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed reclaiming jobs: %w", err)
+	}
+	return reclaimed, nil
+}
+
+// markRunsLost marks the runs of a job which are still recorded as running lost, returning their ids
+func markRunsLost(ctx context.Context, tx *sql.Tx, jobId JobId, now time.Time) ([]RunId, error) {
+	rows, err := tx.QueryContext(ctx, sqlquery.MarkRunsLost, jobId, now, lostRunError)
+	if err != nil {
+		return nil, fmt.Errorf("failed marking runs of job with id %d lost: %w", jobId, err)
+	}
+	defer rows.Close()
+	lost := make([]RunId, 0)
+	for rows.Next() {
+		var id RunId
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed scanning lost run id: %w", err)
+		}
+		lost = append(lost, id)
+	}
+	return lost, rows.Err()
+}
+
+func (st *sqlJobStorage) StartRun(
+	ctx context.Context,
+	job *Job,
+	schedulerId SchedulerId,
+	startTime time.Time,
+) (*Run, error) {
+	run := Run{
+		JobId:       job.Id,
+		JobRevision: job.Revision,
+		SchedulerId: schedulerId,
+		Status:      RunRunning,
+		StartTime:   startTime,
+	}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			sqlquery.StartRun,
+			job.Id,
+			job.Revision,
+			schedulerId,
+			run.Status,
+			startTime,
+		).Scan(&run.Id)
+		if err != nil {
+			err = fmt.Errorf("failed scanning run id: %w", err)
+		}
+		return err
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed starting run of job with id %d: %w", job.Id, err)
+	}
+	return &run, nil
+}
+
+func (st *sqlJobStorage) FinishRun(ctx context.Context, run *Run) error {
+	var userCPUSeconds, systemCPUSeconds *float64
+	var maxRSSBytes *int64
+	if run.Usage != nil {
+		userCPUSeconds = &run.Usage.UserCPUSeconds
+		systemCPUSeconds = &run.Usage.SystemCPUSeconds
+		maxRSSBytes = &run.Usage.MaxRSSBytes
+	}
+	err := st.updateJobs(
+		ctx,
+		sqlquery.FinishRun,
+		run.Status,
+		run.EndTime,
+		run.ExitCode,
+		run.Error,
+		run.TerminatedBy,
+		userCPUSeconds,
+		systemCPUSeconds,
+		maxRSSBytes,
+		run.OutputTail,
+		run.Id,
+	)
+	if err != nil {
+		err = fmt.Errorf("failed finishing run with id %d: %w", run.Id, err)
+	}
+	return err
+}
+
+func (st *sqlJobStorage) GetRuns(ctx context.Context, jobId JobId, limit uint) ([]*Run, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetRuns, jobId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting runs of job with id %d: %w", jobId, err)
+	}
+	defer rows.Close()
+
+	runs := make([]*Run, 0)
+	for rows.Next() {
+		run := Run{}
+		if err = scanRun(rows, &run); err != nil {
+			return nil, fmt.Errorf("failed scanning run of job with id %d: %w", jobId, err)
+		}
+		runs = append(runs, &run)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed getting runs of job with id %d: %w", jobId, err)
+	}
+	return runs, nil
+}
+
+func (st *sqlJobStorage) GetRun(ctx context.Context, jobId JobId, id RunId) (*Run, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	run := Run{}
+	if err := scanRun(st.database.QueryRowContext(ctx, sqlquery.GetRun, jobId, id), &run); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorRunNotFound
+		}
+		return nil, fmt.Errorf("failed getting run %d of job with id %d: %w", id, jobId, err)
+	}
+	return &run, nil
+}
+
+func (st *sqlJobStorage) CancelRun(ctx context.Context, jobId JobId, id RunId) (*Run, error) {
+	run := Run{}
+	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
+		err := scanRun(tx.QueryRowContext(ctx, sqlquery.CancelRun, jobId, id, time.Now()), &run)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err = scanRun(tx.QueryRowContext(ctx, sqlquery.GetRun, jobId, id), &run); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrorRunNotFound
+			}
+			return err
+		}
+		return ErrorRunNotRunning
+	}
+
+	if err := st.transact(ctx, transactionFunc); err != nil {
+		return nil, fmt.Errorf("failed cancelling run %d of job with id %d: %w", id, jobId, err)
+	}
+	return &run, nil
+}
+
+func (st *sqlJobStorage) GetCancelledRuns(ctx context.Context, ids []RunId) ([]RunId, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	params := make([]int64, len(ids))
+	for i, id := range ids {
+		params[i] = int64(id)
+	}
+	rows, err := st.database.QueryContext(ctx, sqlquery.GetCancelledRuns, pq.Array(params))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting cancelled runs: %w", err)
+	}
+	defer rows.Close()
+	cancelled := make([]RunId, 0)
+	for rows.Next() {
+		var id RunId
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed scanning cancelled run id: %w", err)
+		}
+		cancelled = append(cancelled, id)
+	}
+	return cancelled, rows.Err()
+}
+
+func (st *sqlJobStorage) GetJobStats(ctx context.Context, jobId JobId, since time.Time) (*JobStats, error) {
+	st.rwLock.RLock()
+	defer st.rwLock.RUnlock()
+
+	stats := JobStats{JobId: jobId}
+	err := st.database.QueryRowContext(ctx, sqlquery.GetJobStats, jobId, since).Scan(
+		&stats.Runs,
+		&stats.SucceededRuns,
+		&stats.FailedRuns,
+		&stats.DurationP50Seconds,
+		&stats.DurationP95Seconds,
+		&stats.AverageCPUSeconds,
+		&stats.AverageMaxRSSBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting stats of job with id %d: %w", jobId, err)
+	}
+	return &stats, nil
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
 
+// trailingScanner scans columns selected after the ones scanned by a scan function
+type trailingScanner struct {
+	scanner
+	trailing []any
+}
+
+func (ts trailingScanner) Scan(dest ...any) error {
+	return ts.scanner.Scan(append(dest, ts.trailing...)...)
+}
+
 func scanJob(sc scanner, job *Job) error {
-	return sc.Scan(
+	var jobLabels []byte
+	err := sc.Scan(
 		&job.Id,
+		&job.Namespace,
 		&job.Name,
 		&job.CrontabString,
+		&job.Mode,
 		&job.Command,
+		&job.Script,
+		&job.Interpreter,
 		pq.Array(&job.Arguments),
 		&job.Timeout,
+		&job.RunAsUser,
+		&job.RunAsGroup,
+		&job.Limits.AddressSpace,
+		&job.Limits.CPUSeconds,
+		&job.Limits.OpenFiles,
+		&job.Limits.Processes,
+		&job.Revision,
+		&jobLabels,
+		&job.Paused,
+		&job.MaxSuccessInterval,
+	)
+	if err != nil {
+		return err
+	}
+	job.Labels = nil
+	if len(jobLabels) > 0 && string(jobLabels) != "{}" {
+		if err = json.Unmarshal(jobLabels, &job.Labels); err != nil {
+			return fmt.Errorf("failed unmarshalling labels: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanRun(sc scanner, run *Run) error {
+	var userCPUSeconds, systemCPUSeconds sql.NullFloat64
+	var maxRSSBytes, jobRevision, schedulerId sql.NullInt64
+	err := sc.Scan(
+		&run.Id,
+		&run.JobId,
+		&jobRevision,
+		&schedulerId,
+		&run.Status,
+		&run.StartTime,
+		&run.EndTime,
+		&run.ExitCode,
+		&run.Error,
+		&run.TerminatedBy,
+		&userCPUSeconds,
+		&systemCPUSeconds,
+		&maxRSSBytes,
+		&run.OutputTail,
+		&run.CancelRequestedAt,
 	)
+	if err != nil {
+		return err
+	}
+	run.JobRevision = uint(jobRevision.Int64)
+	run.SchedulerId = SchedulerId(schedulerId.Int64)
+	if userCPUSeconds.Valid && systemCPUSeconds.Valid && maxRSSBytes.Valid {
+		run.Usage = &ResourceUsage{userCPUSeconds.Float64, systemCPUSeconds.Float64, maxRSSBytes.Int64}
+	}
+	return nil
 }
 
 func (st *sqlJobStorage) getJobBy(ctx context.Context, query string, params ...any) (Job, error) {
@@ -187,11 +887,6 @@ func (st *sqlJobStorage) transact(ctx context.Context, transactionFunc func(cont
 
 func (st *sqlJobStorage) init(ctx context.Context) error {
 	transactionFunc := func(ctx context.Context, tx *sql.Tx) error {
-		_, err := tx.ExecContext(ctx, sqlquery.ResetState)
-		if err != nil {
-			return fmt.Errorf("error resetting storage state: %w", err)
-		}
-
 		for {
 			rows, err := tx.QueryContext(ctx, sqlquery.FindNullNextExecutionTime)
 			if err != nil {
@@ -1,13 +1,115 @@
 package sqlquery
 
+const jobColumns = "id, namespace, name, crontabString, mode, command, script, interpreter, arguments, timeout, runAsUser, runAsGroup, " +
+	"addressSpaceLimit, cpuLimit, openFilesLimit, processesLimit, revision, labels, paused, maxSuccessInterval"
+
+const runColumns = "id, jobId, jobRevision, schedulerId, status, startTime, endTime, exitCode, error, terminatedBy, userCpuSeconds, systemCpuSeconds, maxRssBytes, outputTail, cancelRequestedAt"
+
+const apiKeyColumns = "id, name, role, namespaces, createdAt, lastUsedAt"
+
+const revisionColumns = "jobId, revision, createdAt, actor, definition"
+
+const auditColumns = "id, recordedAt, actor, requestId, action, jobId, jobName, before, after"
+
 const (
-	NewJob                    = "INSERT INTO jobs (name, crontabString, command, arguments, timeout, nextExecutionTime) values ($1, $2, $3, $4, $5, $6) RETURNING id"
-	GetJob                    = "SELECT id, name, crontabString, command, arguments, timeout FROM jobs WHERE id = $1"
-	DeleteJob                 = "DELETE FROM jobs WHERE id = $1"
-	GetJobByName              = "SELECT id, name, crontabString, command, arguments, timeout FROM jobs WHERE name = $1"
-	MarkDueJobsRunning        = "UPDATE jobs SET running = true WHERE nextExecutionTime <= $1 AND not running RETURNING id, name, crontabString, command, arguments, timeout"
+	NewJob                    = "INSERT INTO jobs (name, crontabString, mode, command, script, interpreter, arguments, timeout, runAsUser, runAsGroup, addressSpaceLimit, cpuLimit, openFilesLimit, processesLimit, nextExecutionTime, labels, namespace, maxSuccessInterval) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id, revision"
+	GetJob                    = "SELECT " + jobColumns + " FROM jobs WHERE id = $1 AND deletedAt IS NULL"
+	DeleteJob                 = "UPDATE jobs SET deletedAt = $2 WHERE id = $1 AND deletedAt IS NULL RETURNING " + jobColumns
+	GetJobForUpdate           = "SELECT " + jobColumns + " FROM jobs WHERE id = $1 AND deletedAt IS NULL FOR UPDATE"
+	UpdateJob                 = "UPDATE jobs SET name = $2, crontabString = $3, mode = $4, command = $5, script = $6, interpreter = $7, arguments = $8, timeout = $9, runAsUser = $10, runAsGroup = $11, addressSpaceLimit = $12, cpuLimit = $13, openFilesLimit = $14, processesLimit = $15, nextExecutionTime = $16, labels = $17, maxSuccessInterval = $18, revision = revision + 1 WHERE id = $1 RETURNING revision"
+	NewJobRevision            = "INSERT INTO jobRevisions (jobId, revision, createdAt, actor, definition) values ($1, $2, $3, $4, $5)"
+	GetJobRevisions           = "SELECT " + revisionColumns + " FROM jobRevisions WHERE jobId = $1 ORDER BY revision DESC"
+	GetJobRevision            = "SELECT " + revisionColumns + " FROM jobRevisions WHERE jobId = $1 AND revision = $2"
+	GetDeletedJob             = "SELECT " + jobColumns + " FROM jobs WHERE id = $1 AND deletedAt IS NOT NULL FOR UPDATE"
+	RestoreJob                = "UPDATE jobs SET deletedAt = NULL, nextExecutionTime = $2 WHERE id = $1"
+	PurgeDeletedJobs          = "DELETE FROM jobs WHERE deletedAt < $1 RETURNING " + jobColumns
+	GetJobByName              = "SELECT " + jobColumns + " FROM jobs WHERE namespace = $1 AND name = $2 AND deletedAt IS NULL"
+	GetJobNamespace           = "SELECT namespace FROM jobs WHERE id = $1"
+	GetDueJobs                = "SELECT " + jobColumns + ", nextExecutionTime FROM jobs WHERE nextExecutionTime <= $1 AND not running AND not paused AND deletedAt IS NULL ORDER BY nextExecutionTime, id FOR UPDATE SKIP LOCKED"
+	MarkJobsRunning           = "UPDATE jobs SET running = true, claimedAt = $2 WHERE id = ANY($1)"
 	MarkDone                  = "UPDATE jobs SET nextExecutionTime = $1, running = false WHERE id = $2"
-	ResetState                = "UPDATE jobs SET nextExecutionTime = NULL, running = false"
-	FindNullNextExecutionTime = "SELECT id, name, crontabString, command, arguments, timeout FROM jobs WHERE nextExecutionTime IS NULL LIMIT 100"
+	GetStuckJobs              = "SELECT " + jobColumns + ", claimedAt FROM jobs WHERE running AND claimedAt + make_interval(secs => timeout + $2) < $1 ORDER BY id FOR UPDATE SKIP LOCKED"
+	CountLiveRuns             = "SELECT count(*) FROM runs JOIN schedulers ON schedulers.id = runs.schedulerId WHERE runs.jobId = $1 AND runs.status = 'running' AND schedulers.stopTime IS NULL AND schedulers.lastHeartbeat > $2"
+	MarkRunsLost              = "UPDATE runs SET status = 'lost', endTime = $2, error = $3 WHERE jobId = $1 AND status = 'running' RETURNING id"
+	FindNullNextExecutionTime = "SELECT " + jobColumns + " FROM jobs WHERE nextExecutionTime IS NULL AND deletedAt IS NULL LIMIT 100"
 	SetNextExecutionTime      = "UPDATE jobs SET nextExecutionTime = $1 WHERE id = $2"
+	TriggerJob                = "UPDATE jobs SET nextExecutionTime = $2 WHERE id = $1 AND NOT running AND NOT paused AND deletedAt IS NULL RETURNING " + jobColumns
+	GetJobState               = "SELECT running, paused FROM jobs WHERE id = $1 AND deletedAt IS NULL"
+	StartRun                  = "INSERT INTO runs (jobId, jobRevision, schedulerId, status, startTime) values ($1, $2, NULLIF($3, 0), $4, $5) RETURNING id"
+	FinishRun                 = "UPDATE runs SET status = $1, endTime = $2, exitCode = $3, error = $4, terminatedBy = $5, userCpuSeconds = $6, systemCpuSeconds = $7, maxRssBytes = $8, outputTail = $9 WHERE id = $10"
+	NewAPIKey                 = "INSERT INTO apiKeys (name, role, namespaces, keyHash, createdAt) values ($1, $2, $3, $4, $5) RETURNING id"
+	GetAPIKeyByHash           = "SELECT " + apiKeyColumns + " FROM apiKeys WHERE keyHash = $1"
+	TouchAPIKey               = "UPDATE apiKeys SET lastUsedAt = $1 WHERE id = $2 AND (lastUsedAt IS NULL OR lastUsedAt < $3)"
+	ListAPIKeys               = "SELECT " + apiKeyColumns + " FROM apiKeys ORDER BY name"
+	DeleteAPIKey              = "DELETE FROM apiKeys WHERE name = $1"
+	GetRuns                   = "SELECT " + runColumns + " FROM runs WHERE jobId = $1 ORDER BY startTime DESC LIMIT $2"
+	GetRun                    = "SELECT " + runColumns + " FROM runs WHERE jobId = $1 AND id = $2"
+	CancelRun                 = "UPDATE runs SET cancelRequestedAt = coalesce(cancelRequestedAt, $3) WHERE jobId = $1 AND id = $2 AND status = 'running' RETURNING " + runColumns
+	GetCancelledRuns          = "SELECT id FROM runs WHERE id = ANY($1) AND cancelRequestedAt IS NOT NULL"
+	NewAuditEntry             = "INSERT INTO audit (recordedAt, actor, requestId, action, jobId, jobName, before, after) values ($1, $2, $3, $4, $5, $6, $7, $8)"
+	GetAuditEntries           = "SELECT " + auditColumns + " FROM audit WHERE ($1::bigint = 0 OR jobId = $1) AND ($2 = '' OR actor = $2) ORDER BY id DESC LIMIT NULLIF($3, 0)"
+	GetJobStats               = "SELECT count(*), count(*) FILTER (WHERE status = 'succeeded'), count(*) FILTER (WHERE status = 'failed'), " +
+		"percentile_cont(0.5) WITHIN GROUP (ORDER BY extract(epoch FROM endTime - startTime)::double precision), " +
+		"percentile_cont(0.95) WITHIN GROUP (ORDER BY extract(epoch FROM endTime - startTime)::double precision), " +
+		"avg(userCpuSeconds + systemCpuSeconds), avg(maxRssBytes) " +
+		"FROM runs WHERE jobId = $1 AND endTime IS NOT NULL AND startTime >= $2"
+	GetSchemaVersion = "SELECT coalesce(max(version), 0) FROM schemaVersion"
+)
+
+const (
+	notificationRuleColumns     = "id, namespace, jobId, events, consecutiveFailures, webhookUrl, webhookFormat, webhookSecret, emailTo, createdAt"
+	notificationDeliveryColumns = "id, ruleId, jobId, runId, events, status, attempts, responseCode, error, createdAt, finishedAt"
+)
+
+const (
+	NewNotificationRule        = "INSERT INTO notificationRules (namespace, jobId, events, consecutiveFailures, webhookUrl, webhookFormat, webhookSecret, emailTo, createdAt) values ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id"
+	GetNotificationRule        = "SELECT " + notificationRuleColumns + " FROM notificationRules WHERE namespace = $1 AND id = $2"
+	ListNotificationRules      = "SELECT " + notificationRuleColumns + " FROM notificationRules WHERE namespace = $1 ORDER BY id"
+	GetJobNotificationRules    = "SELECT " + notificationRuleColumns + " FROM notificationRules WHERE namespace = $1 AND (jobId IS NULL OR jobId = $2) ORDER BY id"
+	DeleteNotificationRule     = "DELETE FROM notificationRules WHERE namespace = $1 AND id = $2"
+	NewNotificationDelivery    = "INSERT INTO notificationDeliveries (ruleId, jobId, runId, events, status, createdAt) values ($1, $2, $3, $4, $5, $6) RETURNING id"
+	UpdateNotificationDelivery = "UPDATE notificationDeliveries SET status = $1, attempts = $2, responseCode = $3, error = $4, finishedAt = $5 WHERE id = $6"
+	GetNotificationDeliveries  = "SELECT " + notificationDeliveryColumns + " FROM notificationDeliveries WHERE ruleId = $1 ORDER BY id DESC LIMIT NULLIF($2, 0)"
+)
+
+// SLA queries measure the time since the end of a job's last successful run, or since its first revision if it
+// never succeeded, with the current time as $1
+const (
+	slaDeadline   = "coalesce(lastSuccess.endTime, created.createdAt) + make_interval(secs => jobs.maxSuccessInterval)"
+	slaViolations = "SELECT jobs.id, jobs.namespace, jobs.name, jobs.maxSuccessInterval, lastSuccess.endTime, " + slaDeadline + ", jobs.slaAlertedAt " +
+		"FROM jobs LEFT JOIN jobRevisions created ON created.jobId = jobs.id AND created.revision = 1 " +
+		"LEFT JOIN LATERAL (SELECT endTime FROM runs WHERE runs.jobId = jobs.id AND runs.status = 'succeeded' ORDER BY startTime DESC LIMIT 1) lastSuccess ON true " +
+		"WHERE jobs.maxSuccessInterval > 0 AND NOT jobs.paused AND jobs.deletedAt IS NULL AND " + slaDeadline + " < $1"
+	GetSLAViolations    = slaViolations + " AND jobs.namespace = $2 ORDER BY jobs.id"
+	GetAllSLAViolations = slaViolations + " ORDER BY jobs.id"
+	MarkSLAViolations   = "UPDATE jobs SET slaAlertedAt = $1 WHERE id = ANY($2) AND slaAlertedAt IS NULL RETURNING id"
+	ClearSLAViolations  = "UPDATE jobs SET slaAlertedAt = NULL WHERE slaAlertedAt IS NOT NULL AND NOT id = ANY($1)"
+)
+
+// Scheduler queries only update schedulers which weren't stopped
+const (
+	RegisterScheduler  = "INSERT INTO schedulers (hostname, pingInterval, version, startTime, lastHeartbeat) values ($1, $2, $3, $4, $4) RETURNING id"
+	HeartbeatScheduler = "UPDATE schedulers SET lastHeartbeat = $1 WHERE id = $2 AND stopTime IS NULL"
+	StopScheduler      = "UPDATE schedulers SET lastHeartbeat = $1, stopTime = $1 WHERE id = $2 AND stopTime IS NULL"
+	ListSchedulers     = "SELECT id, hostname, pingInterval, version, startTime, lastHeartbeat, stopTime FROM schedulers ORDER BY id DESC LIMIT NULLIF($1, 0)"
+)
+
+// Queries on jobs of a namespace selected by labels, with a %s placeholder for the label selector condition
+const (
+	ListJobs   = "SELECT " + jobColumns + " FROM jobs WHERE namespace = $1 AND deletedAt IS NULL AND %s ORDER BY id"
+	PauseJobs  = "UPDATE jobs SET paused = $2 WHERE namespace = $1 AND deletedAt IS NULL AND paused <> $2 AND %s RETURNING " + jobColumns
+	DeleteJobs = "UPDATE jobs SET deletedAt = $2 WHERE namespace = $1 AND deletedAt IS NULL AND %s RETURNING " + jobColumns
+)
+
+// Quota queries lock the quotas of namespaces, serializing the changes which are checked against them
+const (
+	GetQuotasForUpdate = "SELECT namespace, maxJobs, maxConcurrentRuns, maxTimeoutPerHour FROM namespaceQuotas WHERE namespace = ANY($1) ORDER BY namespace FOR UPDATE"
+	GetQuota           = "SELECT namespace, maxJobs, maxConcurrentRuns, maxTimeoutPerHour FROM namespaceQuotas WHERE namespace = $1"
+	SetQuota           = "INSERT INTO namespaceQuotas (namespace, maxJobs, maxConcurrentRuns, maxTimeoutPerHour) values ($1, $2, $3, $4) ON CONFLICT (namespace) DO UPDATE SET maxJobs = $2, maxConcurrentRuns = $3, maxTimeoutPerHour = $4"
+	DeleteQuota        = "DELETE FROM namespaceQuotas WHERE namespace = $1"
+	CountJobs          = "SELECT count(*) FROM jobs WHERE namespace = $1 AND deletedAt IS NULL"
+	CountRunningJobs   = "SELECT count(*) FROM jobs WHERE namespace = $1 AND running"
+	ChargeQuotas       = "INSERT INTO quotaCharges (namespace, chargedAt, timeout) SELECT namespace, $2, timeout FROM jobs WHERE id = ANY($1)"
+	DeleteOldCharges   = "DELETE FROM quotaCharges WHERE chargedAt <= $1"
+	SumChargedTimeouts = "SELECT coalesce(sum(timeout), 0) FROM quotaCharges WHERE namespace = $1 AND chargedAt > $2"
 )
@@ -3,26 +3,115 @@ package model
 import (
 	"context"
 	"errors"
+	"go-work/internal/labels"
+	"time"
 )
 
 type JobId int64
 
+type JobMode string
+
+const (
+	CommandMode JobMode = "command"
+	ShellMode   JobMode = "shell"
+)
+
+// Limits are resource limits applied to a job's process. Zero values mean
+// that the server default is used
+type Limits struct {
+	AddressSpace uint64 `json:"addressSpace,omitempty"`
+	CPUSeconds   uint64 `json:"cpuSeconds,omitempty"`
+	OpenFiles    uint64 `json:"openFiles,omitempty"`
+	Processes    uint64 `json:"processes,omitempty"`
+}
+
 type Job struct {
-	Id            JobId    `json:"id"`
-	Name          string   `json:"name"`
-	CrontabString string   `json:"crontabString"`
-	Command       string   `json:"command"`
-	Arguments     []string `json:"arguments,omitempty"`
-	Timeout       uint     `json:"timeout"`
+	Id            JobId             `json:"id"`
+	Namespace     string            `json:"namespace"`
+	Name          string            `json:"name"`
+	CrontabString string            `json:"crontabString"`
+	Mode          JobMode           `json:"mode"`
+	Command       string            `json:"command,omitempty"`
+	Script        string            `json:"script,omitempty"`
+	Interpreter   string            `json:"interpreter,omitempty"`
+	Arguments     []string          `json:"arguments,omitempty"`
+	Timeout       uint              `json:"timeout"`
+	RunAsUser     string            `json:"runAsUser,omitempty"`
+	RunAsGroup    string            `json:"runAsGroup,omitempty"`
+	Limits        Limits            `json:"limits"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	// MaxSuccessInterval is the number of seconds within which the job is expected to succeed again, 0 disables
+	// the check
+	MaxSuccessInterval uint `json:"maxSuccessInterval,omitempty"`
+	// Revision is incremented on every change of the job's definition
+	Revision uint `json:"revision"`
+	// Paused jobs aren't scheduled. Pausing doesn't change the job's definition
+	Paused bool `json:"paused"`
+	// DueTime is the time the job was due to run, it is only set on jobs returned by MarkDueJobsRunning
+	DueTime time.Time `json:"-"`
 }
 
-var ErrorNotFound = errors.New("job not found")
+var (
+	ErrorNotFound   = errors.New("job not found")
+	ErrorJobExists  = errors.New("job with this name already exists")
+	ErrorJobRunning = errors.New("job is running")
+	ErrorJobPaused  = errors.New("job is paused")
+)
+
+// Storage combines all the storages of the app, which are backed by the same database
+type Storage interface {
+	JobStorage
+	APIKeyStorage
+	AuditStorage
+	QuotaStorage
+	HealthStorage
+	SchedulerStorage
+	NotificationStorage
+	SLAStorage
+}
 
 type JobStorage interface {
-	CreateJob(ctx context.Context, name, crontabString, command string, arguments []string, timeout uint) (JobId, error)
+	CreateJob(ctx context.Context, job *Job) (JobId, error)
 	GetJob(ctx context.Context, id JobId) (*Job, error)
+	// DeleteJob marks the job deleted. It is no longer scheduled or found, but can be
+	// restored until it is purged
 	DeleteJob(ctx context.Context, id JobId) error
-	GetJobByName(ctx context.Context, name string) (*Job, error)
+	RestoreJob(ctx context.Context, id JobId) (*Job, error)
+	// UpdateJob replaces the definition of the job with job.Id, creating a new revision
+	UpdateJob(ctx context.Context, job *Job) (*Job, error)
+	// RollbackJob restores the definition of a previous revision of the job as a new revision
+	RollbackJob(ctx context.Context, id JobId, revision uint) (*Job, error)
+	GetJobRevisions(ctx context.Context, id JobId) ([]*JobRevision, error)
+	GetJobRevision(ctx context.Context, id JobId, revision uint) (*JobRevision, error)
+	// PurgeDeletedJobs permanently removes jobs deleted before the given time, along with their runs
+	PurgeDeletedJobs(ctx context.Context, deletedBefore time.Time) (int, error)
+	GetJobByName(ctx context.Context, namespace string, name string) (*Job, error)
+	// GetJobNamespace returns the namespace of the job with the given id, including deleted jobs
+	GetJobNamespace(ctx context.Context, id JobId) (string, error)
+	// ListJobs returns the jobs of the namespace whose labels match the selector, ordered by id
+	ListJobs(ctx context.Context, namespace string, selector labels.Selector) ([]*Job, error)
+	// PauseJobs pauses or resumes the jobs of the namespace matching a selector, returning the jobs which changed
+	PauseJobs(ctx context.Context, namespace string, selector labels.Selector, paused bool) ([]*Job, error)
+	// DeleteJobs deletes the jobs of the namespace matching a selector the same way as DeleteJob, returning them
+	DeleteJobs(ctx context.Context, namespace string, selector labels.Selector) ([]*Job, error)
 	MarkDueJobsRunning(ctx context.Context) ([]*Job, error)
 	MarkJobDone(ctx context.Context, job *Job) error
+	// TriggerJob makes the job due immediately, so the next scheduler claiming due jobs runs it. Jobs which are
+	// running or paused can't be triggered
+	TriggerJob(ctx context.Context, id JobId) (*Job, error)
+	// ReclaimJobs releases the jobs which are still marked running although their timeout and the grace period
+	// passed since they were claimed, marking their unfinished runs lost. Jobs with a running run whose
+	// scheduler is live are left alone
+	ReclaimJobs(ctx context.Context, grace time.Duration) ([]*ReclaimedJob, error)
+	// StartRun records the start of a run of job by the scheduler, which may be 0 if it isn't registered
+	StartRun(ctx context.Context, job *Job, schedulerId SchedulerId, startTime time.Time) (*Run, error)
+	FinishRun(ctx context.Context, run *Run) error
+	// CancelRun requests the cancellation of a running run of the job. The scheduler running it terminates its
+	// process once it finds the request
+	CancelRun(ctx context.Context, jobId JobId, id RunId) (*Run, error)
+	// GetCancelledRuns returns the ids of the given runs whose cancellation was requested
+	GetCancelledRuns(ctx context.Context, ids []RunId) ([]RunId, error)
+	GetRuns(ctx context.Context, jobId JobId, limit uint) ([]*Run, error)
+	GetRun(ctx context.Context, jobId JobId, id RunId) (*Run, error)
+	GetJobStats(ctx context.Context, jobId JobId, since time.Time) (*JobStats, error)
 }
@@ -0,0 +1,243 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"go-work/internal/model"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	SMTPTLSNone = "none"
+	// SMTPTLSStartTLS upgrades the connection with the STARTTLS command, failing if the server doesn't support it
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSImplicit connects with TLS right away, usually on port 465
+	SMTPTLSImplicit = "tls"
+)
+
+// DefaultSubjectTemplate and DefaultBodyTemplate are used unless SMTPConfig sets templates. Templates are
+// executed with the Payload of a notification, whose Job and Run are nil for test notifications. Besides the
+// builtins, they can call join to join events with a separator and duration to format the duration of a run
+const (
+	DefaultSubjectTemplate = `[go-work] {{if .Job}}{{.Job.Namespace}}/{{.Job.Name}}: {{join .Events ", "}}` +
+		`{{else}}Test notification of rule {{.RuleId}}{{end}}`
+	DefaultBodyTemplate = `{{if .Job -}}
+Job {{.Job.Name}} (id {{.Job.Id}}) in namespace {{.Job.Namespace}} triggered: {{join .Events ", "}}
+{{- with .Run}}
+
+Run:        {{.Id}}
+Status:     {{.Status}}
+Started:    {{.StartTime.Format "2006-01-02T15:04:05Z07:00"}}
+{{- if .EndTime}}
+Finished:   {{.EndTime.Format "2006-01-02T15:04:05Z07:00"}}
+Duration:   {{duration .}}{{end}}
+{{- if .ExitCode}}
+Exit code:  {{.ExitCode}}{{end}}
+{{- if .TerminatedBy}}
+Terminated: exceeded {{.TerminatedBy}}{{end}}
+{{- if .Error}}
+Error:      {{.Error}}{{end}}
+{{- end}}
+{{- with .SLAViolation}}
+
+Expected to succeed every {{.MaxSuccessInterval}}s, {{if .LastSuccess}}last succeeded at {{.LastSuccess.Format "2006-01-02T15:04:05Z07:00"}}{{else}}never succeeded{{end}}
+{{- end}}
+{{- if .ConsecutiveFailures}}
+Consecutive failures: {{.ConsecutiveFailures}}{{end}}
+{{- if .RunURL}}
+Link: {{.RunURL}}{{end}}
+{{- if and .Run .Run.OutputTail}}
+
+Output (tail):
+{{.Run.OutputTail}}{{end}}
+{{- else -}}
+This is a test notification of rule {{.RuleId}}.
+{{- end}}
+`
+)
+
+// SMTPConfig configures the server email notifications are sent through
+type SMTPConfig struct {
+	Host string
+	Port uint
+	// Username enables PLAIN authentication, which net/smtp refuses without TLS unless the server is local
+	Username string
+	Password string
+	// TLS is SMTPTLSNone, SMTPTLSStartTLS or SMTPTLSImplicit
+	TLS  string
+	From string
+	// SubjectTemplate and BodyTemplate are text/template templates, the defaults are used if they're empty
+	SubjectTemplate string
+	BodyTemplate    string
+}
+
+var templateFuncs = template.FuncMap{"join": joinEvents, "duration": runDuration}
+
+// emailSender renders and sends notification emails
+type emailSender struct {
+	config  SMTPConfig
+	from    *mail.Address
+	subject *template.Template
+	body    *template.Template
+	timeout time.Duration
+}
+
+func newEmailSender(config SMTPConfig, timeout time.Duration) (*emailSender, error) {
+	if config.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	switch config.TLS {
+	case SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode \"%s\"", config.TLS)
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address \"%s\": %w", config.From, err)
+	}
+	if config.SubjectTemplate == "" {
+		config.SubjectTemplate = DefaultSubjectTemplate
+	}
+	if config.BodyTemplate == "" {
+		config.BodyTemplate = DefaultBodyTemplate
+	}
+	subject, err := template.New("subject").Funcs(templateFuncs).Parse(config.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing email subject template: %w", err)
+	}
+	body, err := template.New("body").Funcs(templateFuncs).Parse(config.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing email body template: %w", err)
+	}
+	return &emailSender{config, from, subject, body, timeout}, nil
+}
+
+// message renders the email of a delivery
+func (es *emailSender) message(email *model.Email, delivery *model.NotificationDelivery, payload *Payload) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := es.subject.Execute(&subject, payload); err != nil {
+		return nil, fmt.Errorf("failed rendering email subject: %w", err)
+	}
+	if err := es.body.Execute(&body, payload); err != nil {
+		return nil, fmt.Errorf("failed rendering email body: %w", err)
+	}
+	// Header values must not contain line breaks
+	subjectLine := strings.Join(strings.Fields(subject.String()), " ")
+
+	domain := es.from.Address[strings.LastIndexByte(es.from.Address, '@')+1:]
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", es.from.String()},
+		{"To", strings.Join(email.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subjectLine)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<go-work.%d.%d@%s>", delivery.Id, time.Now().UnixNano(), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+		{EventHeader, joinEvents(delivery.Events, ",")},
+		{DeliveryHeader, strconv.FormatInt(int64(delivery.Id), 10)},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	encoder := quotedprintable.NewWriter(&message)
+	if _, err := encoder.Write(bytes.ReplaceAll(body.Bytes(), []byte("\n"), []byte("\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// send delivers a message to the recipients in one SMTP session
+func (es *emailSender) send(ctx context.Context, to []string, message []byte) attemptResult {
+	addr := net.JoinHostPort(es.config.Host, strconv.FormatUint(uint64(es.config.Port), 10))
+	tlsConfig := &tls.Config{ServerName: es.config.Host}
+	dialer := &net.Dialer{Timeout: es.timeout}
+	var conn net.Conn
+	var err error
+	if es.config.TLS == SMTPTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return attemptResult{err: fmt.Errorf("failed connecting to SMTP server: %w", err), retryable: true}
+	}
+	// The deadline bounds the whole session, since net/smtp doesn't take a context
+	deadline := time.Now().Add(es.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, es.config.Host)
+	if err != nil {
+		conn.Close()
+		return smtpResult("failed greeting SMTP server", err)
+	}
+	defer client.Close()
+	if es.config.TLS == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return attemptResult{err: errors.New("SMTP server doesn't support STARTTLS")}
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return smtpResult("failed starting TLS", err)
+		}
+	}
+	if es.config.Username != "" {
+		auth := smtp.PlainAuth("", es.config.Username, es.config.Password, es.config.Host)
+		if err = client.Auth(auth); err != nil {
+			return smtpResult("failed authenticating with SMTP server", err)
+		}
+	}
+	if err = client.Mail(es.from.Address); err != nil {
+		return smtpResult("SMTP server rejected the sender", err)
+	}
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient); err != nil {
+			return smtpResult(fmt.Sprintf("SMTP server rejected recipient %s", recipient), err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return smtpResult("SMTP server rejected the message", err)
+	}
+	if _, err = writer.Write(message); err != nil {
+		return smtpResult("failed sending the message", err)
+	}
+	if err = writer.Close(); err != nil {
+		return smtpResult("SMTP server rejected the message", err)
+	}
+	// The message was accepted, a failing QUIT doesn't matter
+	client.Quit()
+	return attemptResult{}
+}
+
+// smtpResult classifies an SMTP error. Replies with 4xx codes are transient, 5xx codes are permanent
+// and other errors are network errors, which are retried
+func smtpResult(message string, err error) attemptResult {
+	result := attemptResult{err: fmt.Errorf("%s: %w", message, err), retryable: true}
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		code := protocolErr.Code
+		result.responseCode = &code
+		result.retryable = code >= 400 && code < 500
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-work/internal/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// excerptLines and excerptSize limit the output excerpt of chat messages, which only shows the end of the
+	// output kept for the run
+	excerptLines = 10
+	excerptSize  = 1000
+)
+
+// Attachment colors of the slack format
+const (
+	colorFailure = "#d00000"
+	colorSuccess = "#2eb886"
+	colorTest    = "#439fe0"
+)
+
+// slackMessage is a message for Slack and Mattermost incoming webhooks, which both accept attachments
+type slackMessage struct {
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	// Fallback is shown by clients which can't display attachments, e.g. in notifications
+	Fallback   string       `json:"fallback"`
+	Color      string       `json:"color"`
+	Title      string       `json:"title"`
+	TitleLink  string       `json:"title_link,omitempty"`
+	Text       string       `json:"text,omitempty"`
+	Fields     []slackField `json:"fields,omitempty"`
+	MarkdownIn []string     `json:"mrkdwn_in,omitempty"`
+	Footer     string       `json:"footer"`
+	Timestamp  int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// textMessage is a message with only text, which is understood by the incoming webhooks of most chat services
+type textMessage struct {
+	Text string `json:"text"`
+}
+
+// webhookBody encodes a payload in the format of a webhook
+func webhookBody(format model.WebhookFormat, payload *Payload) ([]byte, error) {
+	var message interface{} = payload
+	switch format {
+	case model.WebhookSlack:
+		message = newSlackMessage(payload)
+	case model.WebhookText:
+		message = textMessage{messageText(payload)}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding notification payload: %w", err)
+	}
+	return body, nil
+}
+
+func newSlackMessage(payload *Payload) *slackMessage {
+	attachment := slackAttachment{
+		Fallback:  escapeSlack(summary(payload)),
+		Color:     colorTest,
+		Title:     escapeSlack(title(payload)),
+		TitleLink: payload.RunURL,
+		Footer:    fmt.Sprintf("go-work notification rule %d", payload.RuleId),
+		Timestamp: payload.Time.Unix(),
+	}
+	if violation := payload.SLAViolation; violation != nil {
+		attachment.Color = colorFailure
+		attachment.Fields = []slackField{
+			{"Last success", lastSuccess(violation), true},
+			{"Expected every", successInterval(violation), true},
+		}
+	}
+	if run := payload.Run; run != nil {
+		attachment.Color = colorSuccess
+		if run.Status == model.RunFailed {
+			attachment.Color = colorFailure
+		}
+		attachment.Fields = append(attachment.Fields, slackField{"Status", string(run.Status), true})
+		if duration := runDuration(run); duration != "" {
+			attachment.Fields = append(attachment.Fields, slackField{"Duration", duration, true})
+		}
+		if run.ExitCode != nil {
+			attachment.Fields = append(attachment.Fields, slackField{"Exit code", fmt.Sprint(*run.ExitCode), true})
+		}
+		if run.TerminatedBy != "" {
+			attachment.Fields = append(
+				attachment.Fields,
+				slackField{"Terminated", "exceeded " + string(run.TerminatedBy), true},
+			)
+		}
+		if payload.ConsecutiveFailures > 1 {
+			attachment.Fields = append(
+				attachment.Fields,
+				slackField{"Consecutive failures", fmt.Sprint(payload.ConsecutiveFailures), true},
+			)
+		}
+		if run.Error != "" {
+			attachment.Fields = append(attachment.Fields, slackField{"Error", escapeSlack(run.Error), false})
+		}
+		if excerpt := outputExcerpt(run.OutputTail); excerpt != "" {
+			attachment.Text = "```\n" + escapeSlack(excerpt) + "\n```"
+			attachment.MarkdownIn = []string{"text"}
+		}
+	}
+	return &slackMessage{[]slackAttachment{attachment}}
+}
+
+// messageText renders a payload as a Markdown message
+func messageText(payload *Payload) string {
+	text := summary(payload)
+	if payload.RunURL != "" {
+		text += "\n" + payload.RunURL
+	}
+	if payload.Run != nil {
+		if excerpt := outputExcerpt(payload.Run.OutputTail); excerpt != "" {
+			text += "\n```\n" + excerpt + "\n```"
+		}
+	}
+	return text
+}
+
+func title(payload *Payload) string {
+	if payload.Job == nil {
+		return fmt.Sprintf("Test notification of rule %d", payload.RuleId)
+	}
+	return fmt.Sprintf("%s/%s: %s", payload.Job.Namespace, payload.Job.Name, joinEvents(payload.Events, ", "))
+}
+
+// summary describes a notification in one line
+func summary(payload *Payload) string {
+	if violation := payload.SLAViolation; violation != nil {
+		return fmt.Sprintf(
+			"[go-work] %s, last success %s, expected every %s",
+			title(payload),
+			lastSuccess(violation),
+			successInterval(violation),
+		)
+	}
+	run := payload.Run
+	if run == nil {
+		return "[go-work] " + title(payload)
+	}
+	details := []string{string(run.Status)}
+	if duration := runDuration(run); duration != "" {
+		details = append(details, "after "+duration)
+	}
+	if run.ExitCode != nil {
+		details = append(details, fmt.Sprintf("with exit code %d", *run.ExitCode))
+	}
+	if run.TerminatedBy != "" {
+		details = append(details, "exceeding "+string(run.TerminatedBy))
+	}
+	return fmt.Sprintf("[go-work] %s, run %d %s", title(payload), run.Id, strings.Join(details, " "))
+}
+
+func lastSuccess(violation *model.SLAViolation) string {
+	if violation.LastSuccess == nil {
+		return "never"
+	}
+	return violation.LastSuccess.Format(time.RFC3339)
+}
+
+func successInterval(violation *model.SLAViolation) string {
+	return (time.Duration(violation.MaxSuccessInterval) * time.Second).String()
+}
+
+// outputExcerpt returns the last lines of a run's output. Code fences are broken up, so the output can't
+// end the code block it is shown in
+func outputExcerpt(output string) string {
+	output = strings.TrimRight(output, "\n")
+	if len(output) > excerptSize {
+		start := len(output) - excerptSize
+		for start < len(output) && !utf8.RuneStart(output[start]) {
+			start++
+		}
+		output = output[start:]
+	}
+	if lines := strings.Split(output, "\n"); len(lines) > excerptLines {
+		output = strings.Join(lines[len(lines)-excerptLines:], "\n")
+	}
+	return strings.ReplaceAll(output, "```", "`\u200b``")
+}
+
+// escapeSlack escapes the characters Slack uses for links and mentions
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,419 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/logging"
+	"go-work/internal/metrics"
+	"go-work/internal/model"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logging.ForPackage("notification")
+
+// recordTimeout limits recording deliveries, which happens even after the notifier's context is cancelled
+const recordTimeout = 10 * time.Second
+
+// Storage is the storage notification rules and deliveries are kept in, along with the runs of jobs
+type Storage interface {
+	model.JobStorage
+	model.NotificationStorage
+}
+
+// Config controls how notifications are delivered
+type Config struct {
+	// Attempts is the maximum number of delivery attempts of a notification
+	Attempts uint
+	// Backoff is the delay before the first retry, it doubles with every further retry
+	Backoff time.Duration
+	// Timeout limits each delivery attempt
+	Timeout time.Duration
+	// SMTP is the server email notifications are sent through, email is disabled if it is nil
+	SMTP *SMTPConfig
+	// PublicURL is the URL the API is reachable at, which links to runs start with. Notifications don't
+	// link to runs if it is empty
+	PublicURL string
+}
+
+// JobSummary identifies the job a notification is about
+type JobSummary struct {
+	Id        model.JobId       `json:"id"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Payload is the JSON document notifications are delivered as
+type Payload struct {
+	DeliveryId model.NotificationDeliveryId `json:"deliveryId"`
+	RuleId     model.NotificationRuleId     `json:"ruleId"`
+	Events     []model.NotificationEvent    `json:"events"`
+	Job        *JobSummary                  `json:"job,omitempty"`
+	Run        *model.Run                   `json:"run,omitempty"`
+	// RunURL is the API URL of the run
+	RunURL string `json:"runUrl,omitempty"`
+	// ConsecutiveFailures is the number of failed runs in a row up to and including the run
+	ConsecutiveFailures uint                `json:"consecutiveFailures,omitempty"`
+	SLAViolation        *model.SLAViolation `json:"slaViolation,omitempty"`
+	Time                time.Time           `json:"time"`
+}
+
+// Notifier delivers notifications about finished runs according to the rules which apply to their jobs
+type Notifier struct {
+	storage Storage
+	config  Config
+	client  *http.Client
+	// email is nil if email is disabled
+	email   *emailSender
+	pending *sync.WaitGroup
+}
+
+func New(storage Storage, config Config) (*Notifier, error) {
+	var email *emailSender
+	if config.SMTP != nil {
+		var err error
+		if email, err = newEmailSender(*config.SMTP, config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	client := &http.Client{
+		Timeout: config.Timeout,
+		// Redirects aren't followed, so signed payloads are only sent to the configured URL
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Notifier{storage, config, client, email, &sync.WaitGroup{}}, nil
+}
+
+// EmailEnabled reports whether an SMTP server is configured, which rules sending email require
+func (n *Notifier) EmailEnabled() bool {
+	return n.email != nil
+}
+
+// Wait waits until pending deliveries finish. Retries are abandoned once the context passed to RunFinished
+// is cancelled
+func (n *Notifier) Wait() {
+	n.pending.Wait()
+}
+
+// RunFinished delivers notifications about a finished run in the background
+func (n *Notifier) RunFinished(ctx context.Context, job *model.Job, run *model.Run) {
+	rules, err := n.storage.GetJobNotificationRules(ctx, job)
+	if err != nil {
+		logger.WithContext(ctx).WithFields(logging.JobFields(job)).Errorf("Error getting notification rules: %s", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	outcome, err := n.runOutcome(ctx, job, run, rules)
+	if err != nil {
+		logger.WithContext(ctx).WithFields(logging.JobFields(job)).Errorf("Error getting previous runs to notify about: %s", err)
+		return
+	}
+
+	summary := &JobSummary{job.Id, job.Namespace, job.Name, job.Labels}
+	for _, rule := range rules {
+		events := outcome.events(rule)
+		if len(events) == 0 {
+			continue
+		}
+		payload := Payload{
+			RuleId:              rule.Id,
+			Events:              events,
+			Job:                 summary,
+			Run:                 run,
+			RunURL:              n.runURL(job, run),
+			ConsecutiveFailures: outcome.consecutiveFailures,
+			Time:                time.Now(),
+		}
+		n.deliverInBackground(ctx, rule, &payload)
+	}
+}
+
+// SLAViolated delivers notifications about a job which violates its SLA in the background
+func (n *Notifier) SLAViolated(ctx context.Context, violation *model.SLAViolation) {
+	job, err := n.storage.GetJob(ctx, violation.JobId)
+	if err != nil {
+		logger.WithContext(ctx).WithField(logging.JobIdField, violation.JobId).Errorf("Error getting job to notify about: %s", err)
+		return
+	}
+	rules, err := n.storage.GetJobNotificationRules(ctx, job)
+	if err != nil {
+		logger.WithContext(ctx).WithFields(logging.JobFields(job)).Errorf("Error getting notification rules: %s", err)
+		return
+	}
+
+	summary := &JobSummary{job.Id, job.Namespace, job.Name, job.Labels}
+	for _, rule := range rules {
+		if !hasEvent(rule, model.NotifyOnSLAViolation) {
+			continue
+		}
+		payload := Payload{
+			RuleId:       rule.Id,
+			Events:       []model.NotificationEvent{model.NotifyOnSLAViolation},
+			Job:          summary,
+			SLAViolation: violation,
+			Time:         time.Now(),
+		}
+		n.deliverInBackground(ctx, rule, &payload)
+	}
+}
+
+// Test delivers a test notification through a rule with a single attempt and returns the recorded delivery
+func (n *Notifier) Test(ctx context.Context, rule *model.NotificationRule) (*model.NotificationDelivery, error) {
+	payload := Payload{
+		RuleId: rule.Id,
+		Events: []model.NotificationEvent{model.NotificationTest},
+		Time:   time.Now(),
+	}
+	return n.deliver(ctx, rule, &payload, 1)
+}
+
+func (n *Notifier) deliverInBackground(ctx context.Context, rule *model.NotificationRule, payload *Payload) {
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		if _, err := n.deliver(ctx, rule, payload, n.config.Attempts); err != nil {
+			logger.WithContext(ctx).WithField("rule_id", rule.Id).Errorf("Error delivering notification: %s", err)
+		}
+	}()
+}
+
+func hasEvent(rule *model.NotificationRule, event model.NotificationEvent) bool {
+	for _, ruleEvent := range rule.Events {
+		if ruleEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+// runOutcome determines what the run means for the job, looking back as many runs as the rules need
+func (n *Notifier) runOutcome(
+	ctx context.Context,
+	job *model.Job,
+	run *model.Run,
+	rules []*model.NotificationRule,
+) (*outcome, error) {
+	var lookBack uint = 1
+	for _, rule := range rules {
+		if rule.ConsecutiveFailures > lookBack {
+			lookBack = rule.ConsecutiveFailures
+		}
+	}
+	// The finished run itself is among the most recent ones
+	runs, err := n.storage.GetRuns(ctx, job.Id, lookBack+1)
+	if err != nil {
+		return nil, err
+	}
+	previous := make([]*model.Run, 0, len(runs))
+	for _, previousRun := range runs {
+		if previousRun.Id != run.Id && previousRun.Status != model.RunRunning {
+			previous = append(previous, previousRun)
+		}
+	}
+	return newOutcome(run, previous), nil
+}
+
+// outcome is what a finished run means for its job
+type outcome struct {
+	failed    bool
+	timedOut  bool
+	recovered bool
+	// consecutiveFailures is the number of failed runs in a row up to and including the run, as far
+	// as previous runs were looked at
+	consecutiveFailures uint
+}
+
+// newOutcome determines the outcome of a run given the finished runs preceding it, the most recent first
+func newOutcome(run *model.Run, previous []*model.Run) *outcome {
+	o := outcome{
+		failed:   run.Status == model.RunFailed,
+		timedOut: run.TerminatedBy == model.TerminatedByTimeout,
+	}
+	if !o.failed {
+		o.recovered = run.Status == model.RunSucceeded && len(previous) > 0 && previous[0].Status == model.RunFailed
+		return &o
+	}
+	o.consecutiveFailures = 1
+	for _, previousRun := range previous {
+		if previousRun.Status != model.RunFailed {
+			break
+		}
+		o.consecutiveFailures++
+	}
+	return &o
+}
+
+// runURL returns the API URL of a run, or an empty string without a public URL
+func (n *Notifier) runURL(job *model.Job, run *model.Run) string {
+	if n.config.PublicURL == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s/api/v1/namespaces/%s/job/%d/runs/%d/",
+		strings.TrimSuffix(n.config.PublicURL, "/"),
+		job.Namespace,
+		job.Id,
+		run.Id,
+	)
+}
+
+func joinEvents(events []model.NotificationEvent, separator string) string {
+	values := make([]string, len(events))
+	for i, event := range events {
+		values[i] = string(event)
+	}
+	return strings.Join(values, separator)
+}
+
+// runDuration formats the duration of a finished run, rounded to milliseconds
+func runDuration(run *model.Run) string {
+	if run == nil || run.EndTime == nil {
+		return ""
+	}
+	return run.EndTime.Sub(run.StartTime).Round(time.Millisecond).String()
+}
+
+// events returns the events of the rule which the outcome triggers
+func (o *outcome) events(rule *model.NotificationRule) []model.NotificationEvent {
+	var events []model.NotificationEvent
+	for _, event := range rule.Events {
+		var triggered bool
+		switch event {
+		case model.NotifyOnFailure:
+			triggered = o.failed
+		case model.NotifyOnTimeout:
+			triggered = o.timedOut
+		case model.NotifyOnRecovery:
+			triggered = o.recovered
+		case model.NotifyOnConsecutiveFailures:
+			// Only reaching the threshold triggers the event, so it isn't repeated for every further failure
+			triggered = o.consecutiveFailures == rule.ConsecutiveFailures
+		}
+		if triggered {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// deliver records a delivery and attempts it until it succeeds, fails permanently, runs out of attempts
+// or ctx is cancelled
+func (n *Notifier) deliver(
+	ctx context.Context,
+	rule *model.NotificationRule,
+	payload *Payload,
+	attempts uint,
+) (*model.NotificationDelivery, error) {
+	delivery := model.NotificationDelivery{
+		RuleId:    rule.Id,
+		Events:    payload.Events,
+		Status:    model.DeliveryPending,
+		CreatedAt: time.Now(),
+	}
+	if payload.Job != nil {
+		delivery.JobId = payload.Job.Id
+	}
+	if payload.Run != nil {
+		delivery.RunId = payload.Run.Id
+	}
+	recordCtx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	err := n.storage.CreateNotificationDelivery(recordCtx, &delivery)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	payload.DeliveryId = delivery.Id
+	delivery.Status = model.DeliveryFailed
+	send, err := n.sender(rule, &delivery, payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return n.finishDelivery(&delivery)
+	}
+
+	backoff := n.config.Backoff
+	for delivery.Attempts < attempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				delivery.Error = fmt.Sprintf("%s, retries abandoned: %s", delivery.Error, ctx.Err())
+				return n.finishDelivery(&delivery)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		delivery.Attempts++
+		result := send(ctx)
+		delivery.ResponseCode = result.responseCode
+		delivery.Error = ""
+		if result.err != nil {
+			delivery.Error = result.err.Error()
+		}
+		if result.err == nil {
+			delivery.Status = model.DeliverySucceeded
+			break
+		}
+		if !result.retryable {
+			break
+		}
+	}
+	return n.finishDelivery(&delivery)
+}
+
+// sender prepares delivering a payload through the rule's channel, returning the function which attempts it
+func (n *Notifier) sender(
+	rule *model.NotificationRule,
+	delivery *model.NotificationDelivery,
+	payload *Payload,
+) (func(context.Context) attemptResult, error) {
+	switch {
+	case rule.Webhook != nil:
+		body, err := webhookBody(rule.Webhook.Format, payload)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) attemptResult {
+			return n.sendWebhook(ctx, rule.Webhook, delivery, body)
+		}, nil
+	case rule.Email != nil:
+		if n.email == nil {
+			return nil, errors.New("email notifications aren't configured")
+		}
+		message, err := n.email.message(rule.Email, delivery, payload)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) attemptResult {
+			return n.email.send(ctx, rule.Email.To, message)
+		}, nil
+	}
+	return nil, fmt.Errorf("rule with id %d has no channel", rule.Id)
+}
+
+func (n *Notifier) finishDelivery(delivery *model.NotificationDelivery) (*model.NotificationDelivery, error) {
+	finishedAt := time.Now()
+	delivery.FinishedAt = &finishedAt
+	metrics.NotificationDeliveries.WithLabelValues(string(delivery.Status)).Inc()
+	if delivery.Status == model.DeliveryFailed {
+		logger.WithFields(log.Fields{
+			"rule_id":     delivery.RuleId,
+			"delivery_id": delivery.Id,
+			"attempts":    delivery.Attempts,
+		}).Warnf("Failed to deliver notification: %s", delivery.Error)
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err := n.storage.UpdateNotificationDelivery(recordCtx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
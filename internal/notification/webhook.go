@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-work/internal/model"
+	"go-work/internal/requestid"
+	"go-work/internal/version"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// EventHeader lists the events of a notification, separated by commas
+	EventHeader    = "X-GoWork-Event"
+	DeliveryHeader = "X-GoWork-Delivery"
+	// SignatureHeader holds the hex-encoded HMAC-SHA256 of the request body keyed with the webhook's
+	// secret, prefixed with "sha256=". It is only sent by webhooks with a secret
+	SignatureHeader = "X-GoWork-Signature"
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the value of the signature header of a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// attemptResult is the outcome of a delivery attempt
+type attemptResult struct {
+	responseCode *int
+	err          error
+	// retryable is set if the attempt failed in a way that may not happen again, e.g. a network error
+	retryable bool
+}
+
+func (n *Notifier) sendWebhook(
+	ctx context.Context,
+	webhook *model.Webhook,
+	delivery *model.NotificationDelivery,
+	body []byte,
+) attemptResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return attemptResult{err: fmt.Errorf("failed creating webhook request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-work/"+version.Version)
+	req.Header.Set(EventHeader, joinEvents(delivery.Events, ","))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(int64(delivery.Id), 10))
+	// Test notifications are sent while handling a request, whose id is passed on
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	if webhook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+
+	response, err := n.client.Do(req)
+	if err != nil {
+		return attemptResult{err: fmt.Errorf("failed sending webhook request: %w", err), retryable: true}
+	}
+	// Draining the body lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	response.Body.Close()
+
+	code := response.StatusCode
+	if code >= 200 && code < 300 {
+		return attemptResult{responseCode: &code}
+	}
+	return attemptResult{
+		responseCode: &code,
+		err:          fmt.Errorf("webhook responded with status %d", code),
+		retryable:    code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500,
+	}
+}
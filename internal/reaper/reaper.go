@@ -0,0 +1,55 @@
+package reaper
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/logging"
+	"go-work/internal/metrics"
+	"go-work/internal/model"
+	"time"
+)
+
+var logger = logging.ForPackage("reaper")
+
+// Reaper periodically releases jobs which stayed marked running after their timeout, e.g. because the scheduler
+// running them crashed or failed to mark them done, and records their unfinished runs as lost
+type Reaper struct {
+	storage  model.JobStorage
+	grace    time.Duration
+	interval time.Duration
+}
+
+// New creates a reaper. Jobs are reclaimed once grace passed after their timeout
+func New(storage model.JobStorage, grace time.Duration, interval time.Duration) *Reaper {
+	return &Reaper{storage, grace, interval}
+}
+
+// Start reclaims jobs until the context is cancelled
+func (r *Reaper) Start(ctx context.Context) {
+	for {
+		r.reap(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context) {
+	reclaimed, err := r.storage.ReclaimJobs(ctx, r.grace)
+	if err != nil {
+		logger.Errorf("Error reclaiming jobs: %s", err)
+		return
+	}
+	for _, reclaim := range reclaimed {
+		job := reclaim.Job
+		logger.WithFields(logging.JobFields(job)).WithFields(log.Fields{
+			"claimed_at": reclaim.ClaimedAt,
+			"lost_runs":  reclaim.LostRuns,
+		}).Warn("Reclaimed job which stayed marked running after its timeout")
+		metrics.ReclaimedJobs.WithLabelValues(job.Namespace, job.Name).Inc()
+		lostRuns := metrics.RunsTotal.WithLabelValues(job.Namespace, job.Name, string(model.RunLost), "")
+		lostRuns.Add(float64(len(reclaim.LostRuns)))
+	}
+}
@@ -0,0 +1,44 @@
+package retention
+
+import (
+	"context"
+	"go-work/internal/logging"
+	"go-work/internal/model"
+	"time"
+)
+
+var logger = logging.ForPackage("retention")
+
+// Purger periodically removes jobs which were deleted longer than the retention period ago
+type Purger struct {
+	storage       model.JobStorage
+	retention     time.Duration
+	purgeInterval time.Duration
+}
+
+func New(storage model.JobStorage, retention time.Duration, purgeInterval time.Duration) *Purger {
+	return &Purger{storage, retention, purgeInterval}
+}
+
+// Start purges deleted jobs until the context is cancelled
+func (p *Purger) Start(ctx context.Context) {
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.purgeInterval):
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	purged, err := p.storage.PurgeDeletedJobs(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		logger.Errorf("Error purging deleted jobs: %s", err)
+		return
+	}
+	if purged > 0 {
+		logger.Infof("Purged %d deleted jobs", purged)
+	}
+}
@@ -0,0 +1,114 @@
+package rlimit
+
+import (
+	"errors"
+	"fmt"
+	"go-work/internal/model"
+	"golang.org/x/sys/unix"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// helperCommand is the first argument the go-work executable is re-executed
+// with when it has to apply resource limits before executing a job's command
+const helperCommand = "__go-work-rlimit-exec"
+
+const argumentsSeparator = "--"
+
+var resources = map[string]int{
+	"as":     unix.RLIMIT_AS,
+	"cpu":    unix.RLIMIT_CPU,
+	"nofile": unix.RLIMIT_NOFILE,
+	"nproc":  unix.RLIMIT_NPROC,
+}
+
+func limitArguments(limits model.Limits) []string {
+	arguments := make([]string, 0, len(resources))
+	for name, value := range map[string]uint64{
+		"as":     limits.AddressSpace,
+		"cpu":    limits.CPUSeconds,
+		"nofile": limits.OpenFiles,
+		"nproc":  limits.Processes,
+	} {
+		if value != 0 {
+			arguments = append(arguments, fmt.Sprintf("%s=%d", name, value))
+		}
+	}
+	return arguments
+}
+
+// Wrap makes cmd start through the running executable, which applies limits to
+// itself and then replaces itself with the original command. Limits are thus
+// in place before the command's executable is loaded. Any executable linking
+// this package can act as the helper, e.g. test binaries or ones embedding the server
+func Wrap(cmd *exec.Cmd, limits model.Limits) error {
+	arguments := limitArguments(limits)
+	if len(arguments) == 0 {
+		return nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed finding the running executable: %w", err)
+	}
+	path := cmd.Path
+	if !strings.Contains(path, "/") {
+		if path, err = exec.LookPath(path); err != nil {
+			return fmt.Errorf("failed finding executable \"%s\": %w", cmd.Path, err)
+		}
+	}
+
+	wrappedArgs := []string{executable, helperCommand}
+	wrappedArgs = append(wrappedArgs, arguments...)
+	wrappedArgs = append(wrappedArgs, argumentsSeparator, path)
+	cmd.Args = append(wrappedArgs, cmd.Args...)
+	cmd.Path = executable
+	return nil
+}
+
+// init runs before main and before the flags of tests are parsed, so it doesn't depend
+// on the program. When the process has been started by Wrap, it applies the limits
+// and executes the wrapped command, never returning
+func init() {
+	if len(os.Args) < 2 || os.Args[1] != helperCommand {
+		return
+	}
+	err := execWithLimits(os.Args[2:])
+	fmt.Fprintf(os.Stderr, "go-work: failed executing command with resource limits: %s\n", err)
+	os.Exit(127)
+}
+
+func execWithLimits(arguments []string) error {
+	for i, argument := range arguments {
+		if argument == argumentsSeparator {
+			if len(arguments) < i+3 {
+				return errors.New("missing command")
+			}
+			return syscall.Exec(arguments[i+1], arguments[i+2:], os.Environ())
+		}
+		name, value, _ := strings.Cut(argument, "=")
+		resource, ok := resources[name]
+		if !ok {
+			return fmt.Errorf("unknown resource \"%s\"", name)
+		}
+		limit, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed parsing limit of resource \"%s\": %w", name, err)
+		}
+		rlimit := syscall.Rlimit{Cur: limit, Max: limit}
+		if resource == unix.RLIMIT_CPU {
+			// The kernel sends SIGXCPU at the soft limit, but kills with SIGKILL right away if
+			// the hard limit is reached at the same time. The hard limit is one second higher,
+			// so processes are terminated with SIGXCPU, which can be told apart from other kills
+			rlimit.Max = limit + 1
+		}
+		// syscall.Setrlimit is used instead of unix.Setrlimit so that the runtime doesn't
+		// restore its own saved RLIMIT_NOFILE when executing the command
+		if err = syscall.Setrlimit(resource, &rlimit); err != nil {
+			return fmt.Errorf("failed setting limit of resource \"%s\": %w", name, err)
+		}
+	}
+	return errors.New("missing command")
+}
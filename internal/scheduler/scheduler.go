@@ -2,30 +2,310 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"go-work/internal/execution"
+	"go-work/internal/logging"
+	"go-work/internal/metrics"
 	"go-work/internal/model"
+	"go-work/internal/notification"
+	"go-work/internal/tracing"
+	"go-work/internal/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"os"
 	"os/exec"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var logger = logging.ForPackage("scheduler")
+
+// A scheduler is reported unhealthy when its last successful tick is older than staleTicks ping
+// intervals plus staleTickGrace, which allows for slow ticks
+const (
+	staleTicks     = 3
+	staleTickGrace = 30 * time.Second
+	// stopTimeout limits recording that the scheduler stopped, which happens after its context is cancelled
+	stopTimeout = 10 * time.Second
+)
+
+// Storage is the storage schedulers claim due jobs from and register themselves in
+type Storage interface {
+	model.JobStorage
+	model.SchedulerStorage
+}
+
 type Scheduler struct {
-	storage      model.JobStorage
+	storage      Storage
 	pingInterval time.Duration
-	doneChannel  chan model.Job
-	stopWg       *sync.WaitGroup
+	config       *execution.Config
+	// notifier is nil if notifications are disabled
+	notifier    *notification.Notifier
+	doneChannel chan model.Job
+	stopWg      *sync.WaitGroup
+	state       *tickState
+	// unregisteredLabel identifies the scheduler in metrics until it is registered
+	unregisteredLabel string
+}
+
+// schedulerCount numbers the schedulers of the process for their unregistered labels
+var schedulerCount int32
+
+// tickState records the progress of the scheduler's loop for health checks
+type tickState struct {
+	lock               sync.Mutex
+	id                 model.SchedulerId
+	startTime          time.Time
+	lastTick           time.Time
+	lastSuccessfulTick time.Time
+	lastError          string
+	runningJobs        int
+	// runs are the runs executed by the scheduler, by their ids
+	runs map[model.RunId]*runningRun
+}
+
+// runningRun is a run executed by the scheduler, which is cancelled when its cancellation is requested
+type runningRun struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// Status reports the progress of a scheduler's loop
+type Status struct {
+	// Id is the id the scheduler is registered with, it is absent until registration succeeds
+	Id                  model.SchedulerId `json:"id,omitempty"`
+	Name                string            `json:"name"`
+	PingIntervalSeconds float64           `json:"pingIntervalSeconds"`
+	StartTime           *time.Time        `json:"startTime,omitempty"`
+	LastTick            *time.Time        `json:"lastTick,omitempty"`
+	LastSuccessfulTick  *time.Time        `json:"lastSuccessfulTick,omitempty"`
+	LastError           string            `json:"lastError,omitempty"`
+	RunningJobs         int               `json:"runningJobs"`
+	// Healthy is false if the scheduler isn't running or hasn't successfully claimed due jobs
+	// for several ping intervals, e.g. because its loop is stuck
+	Healthy bool `json:"healthy"`
 }
 
-func New(storage model.JobStorage, pingInterval time.Duration) *Scheduler {
-	skd := Scheduler{storage, pingInterval, make(chan model.Job), &sync.WaitGroup{}}
-	skd.stopWg.Add(2)
+func New(
+	storage Storage,
+	pingInterval time.Duration,
+	config *execution.Config,
+	notifier *notification.Notifier,
+) *Scheduler {
+	skd := Scheduler{
+		storage,
+		pingInterval,
+		config,
+		notifier,
+		make(chan model.Job),
+		&sync.WaitGroup{},
+		&tickState{runs: make(map[model.RunId]*runningRun)},
+		fmt.Sprintf("unregistered-%d", atomic.AddInt32(&schedulerCount, 1)),
+	}
+	skd.stopWg.Add(3)
 	return &skd
 }
 
+// name describes the scheduler by its interval
+func (skd *Scheduler) name() string {
+	return skd.pingInterval.String()
+}
+
+// metricsLabel identifies the scheduler in metrics by its registry id, which is unique even among
+// schedulers with the same interval
+func (skd *Scheduler) metricsLabel() string {
+	if id := skd.id(); id != 0 {
+		return strconv.FormatInt(int64(id), 10)
+	}
+	return skd.unregisteredLabel
+}
+
 func (skd *Scheduler) Start(ctx context.Context) {
+	skd.state.lock.Lock()
+	skd.state.startTime = time.Now()
+	skd.state.lock.Unlock()
+	defer func() {
+		skd.state.lock.Lock()
+		skd.state.startTime = time.Time{}
+		skd.state.lock.Unlock()
+	}()
+
+	skd.register(ctx)
 	go skd.startDueJobs(ctx)
 	go skd.monitorDone(ctx)
+	go skd.sendHeartbeats(ctx)
 	skd.stopWg.Wait()
+	skd.stop()
+}
+
+func (skd *Scheduler) id() model.SchedulerId {
+	skd.state.lock.Lock()
+	defer skd.state.lock.Unlock()
+	return skd.state.id
+}
+
+// register records the scheduler in the storage. If it fails, registering is retried with the next heartbeat
+// and runs started until then aren't attributed to the scheduler
+func (skd *Scheduler) register(ctx context.Context) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Warnf("Could not get hostname to register scheduler with: %s", err)
+	}
+	instance := model.SchedulerInstance{
+		Hostname:            hostname,
+		PingIntervalSeconds: skd.pingInterval.Seconds(),
+		Version:             version.Version,
+	}
+	if err = skd.storage.RegisterScheduler(ctx, &instance); err != nil {
+		logger.Errorf("Error registering scheduler: %s", err)
+		return
+	}
+	skd.state.lock.Lock()
+	skd.state.id = instance.Id
+	skd.state.lock.Unlock()
+	logger.WithFields(log.Fields{
+		logging.SchedulerIdField: instance.Id,
+		"interval":               skd.name(),
+	}).Info("Registered scheduler")
+}
+
+// sendHeartbeats records that the scheduler is alive and, along with that, cancels the runs whose
+// cancellation was requested
+func (skd *Scheduler) sendHeartbeats(ctx context.Context) {
+	defer skd.stopWg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(model.SchedulerHeartbeatInterval):
+			skd.cancelRuns(ctx)
+			id := skd.id()
+			if id == 0 {
+				skd.register(ctx)
+				continue
+			}
+			err := skd.storage.HeartbeatScheduler(ctx, id)
+			if errors.Is(err, model.ErrorSchedulerNotFound) {
+				logger.WithField(logging.SchedulerIdField, id).Warn("Scheduler is no longer registered, registering it again")
+				skd.register(ctx)
+			} else if err != nil {
+				logger.WithField(logging.SchedulerIdField, id).Errorf("Error sending scheduler heartbeat: %s", err)
+			}
+		}
+	}
+}
+
+// stop records that the scheduler stopped, so it isn't mistaken for a dead one
+func (skd *Scheduler) stop() {
+	id := skd.id()
+	if id == 0 {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := skd.storage.StopScheduler(timeoutCtx, id); err != nil {
+		logger.WithField(logging.SchedulerIdField, id).Errorf("Error recording stop of scheduler: %s", err)
+	}
+}
+
+// Status returns the progress of the scheduler's loop at the given time
+func (skd *Scheduler) Status(now time.Time) Status {
+	skd.state.lock.Lock()
+	defer skd.state.lock.Unlock()
+
+	status := Status{
+		Id:                  skd.state.id,
+		Name:                skd.name(),
+		PingIntervalSeconds: skd.pingInterval.Seconds(),
+		StartTime:           timeOrNil(skd.state.startTime),
+		LastTick:            timeOrNil(skd.state.lastTick),
+		LastSuccessfulTick:  timeOrNil(skd.state.lastSuccessfulTick),
+		LastError:           skd.state.lastError,
+		RunningJobs:         skd.state.runningJobs,
+	}
+	if !skd.state.startTime.IsZero() {
+		progress := skd.state.startTime
+		if skd.state.lastSuccessfulTick.After(progress) {
+			progress = skd.state.lastSuccessfulTick
+		}
+		status.Healthy = now.Sub(progress) <= staleTicks*skd.pingInterval+staleTickGrace
+	}
+	return status
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// recordTick records the outcome of claiming due jobs
+func (skd *Scheduler) recordTick(err error) {
+	skd.state.lock.Lock()
+	defer skd.state.lock.Unlock()
+
+	skd.state.lastTick = time.Now()
+	if err != nil {
+		skd.state.lastError = err.Error()
+		return
+	}
+	skd.state.lastSuccessfulTick = skd.state.lastTick
+	skd.state.lastError = ""
+}
+
+// trackRun makes a run cancellable through the storage
+func (skd *Scheduler) trackRun(id model.RunId, cancel context.CancelFunc) {
+	skd.state.lock.Lock()
+	skd.state.runs[id] = &runningRun{cancel: cancel}
+	skd.state.lock.Unlock()
+}
+
+// untrackRun stops tracking a run, returning whether it was cancelled
+func (skd *Scheduler) untrackRun(id model.RunId) bool {
+	skd.state.lock.Lock()
+	defer skd.state.lock.Unlock()
+	run, ok := skd.state.runs[id]
+	delete(skd.state.runs, id)
+	return ok && run.cancelled
+}
+
+// cancelRuns cancels the runs of the scheduler whose cancellation was requested
+func (skd *Scheduler) cancelRuns(ctx context.Context) {
+	skd.state.lock.Lock()
+	ids := make([]model.RunId, 0, len(skd.state.runs))
+	for id := range skd.state.runs {
+		ids = append(ids, id)
+	}
+	skd.state.lock.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	cancelled, err := skd.storage.GetCancelledRuns(ctx, ids)
+	if err != nil {
+		logger.WithField(logging.SchedulerIdField, skd.id()).Errorf("Error getting cancelled runs: %s", err)
+		return
+	}
+	skd.state.lock.Lock()
+	defer skd.state.lock.Unlock()
+	for _, id := range cancelled {
+		if run, ok := skd.state.runs[id]; ok && !run.cancelled {
+			run.cancelled = true
+			run.cancel()
+		}
+	}
+}
+
+func (skd *Scheduler) addRunningJobs(delta int) {
+	skd.state.lock.Lock()
+	skd.state.runningJobs += delta
+	skd.state.lock.Unlock()
 }
 
 func (skd *Scheduler) startDueJobs(ctx context.Context) {
@@ -35,42 +315,152 @@ func (skd *Scheduler) startDueJobs(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-time.After(skd.pingInterval):
-			jobs, err := skd.storage.MarkDueJobsRunning(ctx)
+			// Runs are traced as children of the claim, so a run can be correlated with the
+			// scheduler which started it
+			claimCtx, span := tracing.Tracer.Start(
+				ctx,
+				"scheduler.claim",
+				trace.WithAttributes(attribute.String("scheduler", skd.name())),
+			)
+			jobs, err := skd.storage.MarkDueJobsRunning(claimCtx)
 			if err != nil {
-				log.Errorf("Error marking due jobs running: %s", err)
+				logger.WithField(logging.SchedulerIdField, skd.id()).Errorf("Error marking due jobs running: %s", err)
+				span.SetStatus(codes.Error, err.Error())
 			}
+			span.SetAttributes(attribute.Int("jobs", len(jobs)))
+			span.End()
+			skd.recordTick(err)
 
 			for _, job := range jobs {
-				go func(job *model.Job) {
-					defer func() {
-						if rec := recover(); rec != nil {
-							log.Errorf("Panic while executing job: %s", rec)
-						}
-					}()
-
-					timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(job.Timeout))
-					log.WithFields(log.Fields{
-						"job": job,
-					}).Info("Executing job")
-					err = exec.CommandContext(timeoutCtx, job.Command, job.Arguments...).Run()
-					cancel()
-					if err != nil {
-						log.WithFields(log.Fields{
-							"job": job,
-						}).Errorf("Error executing job: %s", err)
-					}
-					err = skd.storage.MarkJobDone(ctx, job)
-					if err != nil {
-						log.WithFields(log.Fields{
-							"job": job,
-						}).Errorf("Error marking job done: %s", err)
-					}
-				}(job)
+				go skd.runJob(claimCtx, job)
 			}
 		}
 	}
 }
 
+func (skd *Scheduler) runJob(ctx context.Context, job *model.Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.WithFields(logging.JobFields(job)).Errorf("Panic while executing job: %s", rec)
+		}
+	}()
+
+	label := skd.metricsLabel()
+	runningJobs := metrics.RunningJobs.WithLabelValues(label)
+	runningJobs.Inc()
+	defer runningJobs.Dec()
+	skd.addRunningJobs(1)
+	defer skd.addRunningJobs(-1)
+
+	ctx, span := tracing.Tracer.Start(ctx, "job.run", trace.WithAttributes(
+		attribute.Int64("job.id", int64(job.Id)),
+		attribute.String("job.namespace", job.Namespace),
+		attribute.String("job.name", job.Name),
+		attribute.Int64("scheduler.id", int64(skd.id())),
+	))
+	defer span.End()
+
+	startTime := time.Now()
+	if !job.DueTime.IsZero() {
+		metrics.ScheduleLag.WithLabelValues(label).Observe(startTime.Sub(job.DueTime).Seconds())
+	}
+	run, err := skd.storage.StartRun(ctx, job, skd.id(), startTime)
+	if err != nil {
+		skd.runLogger(ctx, job, nil).Errorf("Error recording start of job run: %s", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(job.Timeout))
+	if run != nil {
+		span.SetAttributes(attribute.Int64("run.id", int64(run.Id)))
+		skd.trackRun(run.Id, cancel)
+	}
+	entry := skd.runLogger(ctx, job, run)
+	entry.Info("Executing job")
+	output := execution.NewTailBuffer(execution.OutputTailSize)
+	cmd, err := skd.config.Command(timeoutCtx, job)
+	if err == nil {
+		cmd.Stdout = output
+		cmd.Stderr = output
+		err = cmd.Run()
+	}
+	timedOut := errors.Is(timeoutCtx.Err(), context.DeadlineExceeded)
+	// A process which finished before its cancellation took effect keeps its outcome
+	cancelled := run != nil && skd.untrackRun(run.Id) && err != nil
+	cancel()
+	if err != nil {
+		entry.Errorf("Error executing job: %s", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if run != nil {
+		run.OutputTail = output.String()
+		skd.finishRun(ctx, job, run, cmd, err, timedOut, cancelled)
+		if run.ExitCode != nil {
+			span.SetAttributes(attribute.Int("run.exit_code", *run.ExitCode))
+		}
+		if run.TerminatedBy != "" {
+			span.SetAttributes(attribute.String("run.terminated_by", string(run.TerminatedBy)))
+		}
+		if skd.notifier != nil {
+			skd.notifier.RunFinished(ctx, job, run)
+		}
+	}
+	err = skd.storage.MarkJobDone(ctx, job)
+	if err != nil {
+		entry.Errorf("Error marking job done: %s", err)
+	}
+}
+
+// runLogger returns the logger of a job's run, which may be nil if its start couldn't be recorded
+func (skd *Scheduler) runLogger(ctx context.Context, job *model.Job, run *model.Run) *log.Entry {
+	entry := logger.WithContext(ctx).WithFields(logging.JobFields(job)).WithField(logging.SchedulerIdField, skd.id())
+	if run != nil {
+		entry = entry.WithField(logging.RunIdField, run.Id)
+	}
+	return entry
+}
+
+func (skd *Scheduler) finishRun(
+	ctx context.Context,
+	job *model.Job,
+	run *model.Run,
+	cmd *exec.Cmd,
+	runErr error,
+	timedOut bool,
+	cancelled bool,
+) {
+	endTime := time.Now()
+	run.EndTime = &endTime
+	run.Status = model.RunSucceeded
+	if runErr != nil {
+		run.Status = model.RunFailed
+		run.Error = runErr.Error()
+	}
+	if cmd != nil && cmd.ProcessState != nil {
+		exitCode := cmd.ProcessState.ExitCode()
+		run.ExitCode = &exitCode
+		run.TerminatedBy = execution.TerminationCause(cmd.ProcessState, skd.config.Limits(job))
+		run.Usage = execution.ResourceUsage(cmd.ProcessState)
+	}
+	if timedOut {
+		run.TerminatedBy = model.TerminatedByTimeout
+	} else if cancelled {
+		run.Status = model.RunCancelled
+		skd.runLogger(ctx, job, run).Info("Job run was cancelled")
+	}
+	if run.TerminatedBy != "" {
+		skd.runLogger(ctx, job, run).
+			WithField("terminated_by", run.TerminatedBy).
+			Warn("Job was terminated after exceeding a limit")
+	}
+	metrics.RunsTotal.WithLabelValues(job.Namespace, job.Name, string(run.Status), string(run.TerminatedBy)).Inc()
+	metrics.RunDuration.WithLabelValues(job.Namespace, job.Name).Observe(endTime.Sub(run.StartTime).Seconds())
+
+	if err := skd.storage.FinishRun(ctx, run); err != nil {
+		skd.runLogger(ctx, job, run).Errorf("Error recording end of job run: %s", err)
+	}
+}
+
 func (skd *Scheduler) monitorDone(ctx context.Context) {
 	defer skd.stopWg.Done()
 	for {
@@ -78,9 +468,7 @@ func (skd *Scheduler) monitorDone(ctx context.Context) {
 		case job := <-skd.doneChannel:
 			err := skd.storage.MarkJobDone(ctx, &job)
 			if err != nil {
-				log.WithFields(log.Fields{
-					"job": job,
-				}).Errorf("Error signaling completion of job: %s", err)
+				logger.WithFields(logging.JobFields(&job)).Errorf("Error signaling completion of job: %s", err)
 			}
 		case <-ctx.Done():
 			return
@@ -0,0 +1,107 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxScriptSize is the maximum size in bytes of a shell-mode job script
+const MaxScriptSize = 64 * 1024
+
+// DefaultInterpreter is used for shell-mode jobs that don't specify an interpreter
+const DefaultInterpreter = "sh"
+
+const stdinArgument = "-"
+
+type Interpreter struct {
+	Name       string
+	Executable string
+	Args       []string
+}
+
+type Interpreters map[string]Interpreter
+
+func DefaultInterpreters() Interpreters {
+	return Interpreters{
+		"sh":      {"sh", "/bin/sh", []string{"-c"}},
+		"bash":    {"bash", "bash", []string{"-c"}},
+		"python3": {"python3", "python3", []string{stdinArgument}},
+	}
+}
+
+// ParseInterpreter parses an interpreter definition of the form "name=command [args...]".
+// If the last argument is "-", the script is passed to the interpreter through stdin,
+// otherwise it is appended to the arguments
+func ParseInterpreter(definition string) (Interpreter, error) {
+	name, commandLine, found := strings.Cut(definition, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return Interpreter{}, fmt.Errorf("interpreter definition \"%s\" must be of the form name=command [args...]", definition)
+	}
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return Interpreter{}, fmt.Errorf("interpreter definition \"%s\" has an empty command", definition)
+	}
+	return Interpreter{name, fields[0], fields[1:]}, nil
+}
+
+func ParseInterpreters(definitions []string) (Interpreters, error) {
+	if len(definitions) == 0 {
+		return DefaultInterpreters(), nil
+	}
+	interpreters := make(Interpreters, len(definitions))
+	for _, definition := range definitions {
+		interpreter, err := ParseInterpreter(definition)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := interpreters[interpreter.Name]; ok {
+			return nil, fmt.Errorf("interpreter \"%s\" is defined more than once", interpreter.Name)
+		}
+		interpreters[interpreter.Name] = interpreter
+	}
+	return interpreters, nil
+}
+
+func (in Interpreter) readsStdin() bool {
+	return len(in.Args) > 0 && in.Args[len(in.Args)-1] == stdinArgument
+}
+
+// Command returns a command running script with the interpreter. Arguments are passed
+// to the script as positional parameters, with name used as $0 for "-c" style interpreters
+func (in Interpreter) Command(ctx context.Context, script, name string, arguments []string) *exec.Cmd {
+	args := append([]string{}, in.Args...)
+	if in.readsStdin() {
+		args = append(args, arguments...)
+		cmd := exec.CommandContext(ctx, in.Executable, args...)
+		cmd.Stdin = strings.NewReader(script)
+		return cmd
+	}
+	args = append(args, script, name)
+	args = append(args, arguments...)
+	return exec.CommandContext(ctx, in.Executable, args...)
+}
+
+var (
+	ErrorScriptTooLarge  = fmt.Errorf("script exceeds the maximum size of %d bytes", MaxScriptSize)
+	ErrorScriptNotUTF8   = errors.New("script is not valid UTF-8")
+	ErrorScriptNullBytes = errors.New("script contains null bytes")
+)
+
+// ValidateScript checks that script can be safely stored and passed to an interpreter
+func ValidateScript(script string) error {
+	if len(script) > MaxScriptSize {
+		return ErrorScriptTooLarge
+	}
+	if !utf8.ValidString(script) {
+		return ErrorScriptNotUTF8
+	}
+	if strings.IndexByte(script, 0) != -1 {
+		return ErrorScriptNullBytes
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package sla
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"go-work/internal/logging"
+	"go-work/internal/metrics"
+	"go-work/internal/model"
+	"go-work/internal/notification"
+	"time"
+)
+
+var logger = logging.ForPackage("sla")
+
+// Checker periodically looks for jobs which haven't succeeded within their maximum success interval and
+// alerts about each violation once
+type Checker struct {
+	storage       model.SLAStorage
+	notifier      *notification.Notifier
+	checkInterval time.Duration
+}
+
+// New creates a checker. Violations are only logged and counted if notifier is nil
+func New(storage model.SLAStorage, notifier *notification.Notifier, checkInterval time.Duration) *Checker {
+	return &Checker{storage, notifier, checkInterval}
+}
+
+// Start checks SLAs until the context is cancelled
+func (c *Checker) Start(ctx context.Context) {
+	for {
+		c.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.checkInterval):
+		}
+	}
+}
+
+func (c *Checker) check(ctx context.Context) {
+	violations, err := c.storage.MarkSLAViolations(ctx)
+	if err != nil {
+		logger.Errorf("Error checking SLAs: %s", err)
+		return
+	}
+	for _, violation := range violations {
+		logger.WithFields(log.Fields{
+			logging.JobIdField:     violation.JobId,
+			logging.NamespaceField: violation.Namespace,
+			logging.JobNameField:   violation.JobName,
+			"max_success_interval": violation.MaxSuccessInterval,
+			"since":                violation.Since,
+		}).Warn("Job hasn't succeeded within its maximum success interval")
+		metrics.SLAViolations.WithLabelValues(violation.Namespace, violation.JobName).Inc()
+		if c.notifier != nil {
+			c.notifier.SLAViolated(ctx, violation)
+		}
+	}
+}
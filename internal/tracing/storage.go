@@ -0,0 +1,322 @@
+package tracing
+
+import (
+	"context"
+	"go-work/internal/auth"
+	"go-work/internal/labels"
+	"go-work/internal/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// TraceStorage returns a storage which records a span for every operation of storage
+func TraceStorage(storage model.Storage) model.Storage {
+	return &tracedStorage{storage}
+}
+
+type tracedStorage struct {
+	storage model.Storage
+}
+
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return Tracer.Start(ctx, "storage."+operation, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// endSpan ends the span of an operation. It is deferred with a pointer to the operation's error,
+// which is only known once it returns
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+func (ts *tracedStorage) CreateJob(ctx context.Context, job *model.Job) (id model.JobId, err error) {
+	ctx, span := startSpan(ctx, "CreateJob")
+	defer endSpan(span, &err)
+	return ts.storage.CreateJob(ctx, job)
+}
+
+func (ts *tracedStorage) GetJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	ctx, span := startSpan(ctx, "GetJob")
+	defer endSpan(span, &err)
+	return ts.storage.GetJob(ctx, id)
+}
+
+func (ts *tracedStorage) DeleteJob(ctx context.Context, id model.JobId) (err error) {
+	ctx, span := startSpan(ctx, "DeleteJob")
+	defer endSpan(span, &err)
+	return ts.storage.DeleteJob(ctx, id)
+}
+
+func (ts *tracedStorage) RestoreJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	ctx, span := startSpan(ctx, "RestoreJob")
+	defer endSpan(span, &err)
+	return ts.storage.RestoreJob(ctx, id)
+}
+
+func (ts *tracedStorage) UpdateJob(ctx context.Context, job *model.Job) (updated *model.Job, err error) {
+	ctx, span := startSpan(ctx, "UpdateJob")
+	defer endSpan(span, &err)
+	return ts.storage.UpdateJob(ctx, job)
+}
+
+func (ts *tracedStorage) RollbackJob(ctx context.Context, id model.JobId, revision uint) (job *model.Job, err error) {
+	ctx, span := startSpan(ctx, "RollbackJob")
+	defer endSpan(span, &err)
+	return ts.storage.RollbackJob(ctx, id, revision)
+}
+
+func (ts *tracedStorage) GetJobRevisions(ctx context.Context, id model.JobId) (revisions []*model.JobRevision, err error) {
+	ctx, span := startSpan(ctx, "GetJobRevisions")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobRevisions(ctx, id)
+}
+
+func (ts *tracedStorage) GetJobRevision(ctx context.Context, id model.JobId, revision uint) (jobRevision *model.JobRevision, err error) {
+	ctx, span := startSpan(ctx, "GetJobRevision")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobRevision(ctx, id, revision)
+}
+
+func (ts *tracedStorage) PurgeDeletedJobs(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	ctx, span := startSpan(ctx, "PurgeDeletedJobs")
+	defer endSpan(span, &err)
+	return ts.storage.PurgeDeletedJobs(ctx, deletedBefore)
+}
+
+func (ts *tracedStorage) GetJobByName(ctx context.Context, namespace string, name string) (job *model.Job, err error) {
+	ctx, span := startSpan(ctx, "GetJobByName")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobByName(ctx, namespace, name)
+}
+
+func (ts *tracedStorage) GetJobNamespace(ctx context.Context, id model.JobId) (namespace string, err error) {
+	ctx, span := startSpan(ctx, "GetJobNamespace")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobNamespace(ctx, id)
+}
+
+func (ts *tracedStorage) ListJobs(ctx context.Context, namespace string, selector labels.Selector) (jobs []*model.Job, err error) {
+	ctx, span := startSpan(ctx, "ListJobs")
+	defer endSpan(span, &err)
+	return ts.storage.ListJobs(ctx, namespace, selector)
+}
+
+func (ts *tracedStorage) PauseJobs(ctx context.Context, namespace string, selector labels.Selector, paused bool) (jobs []*model.Job, err error) {
+	ctx, span := startSpan(ctx, "PauseJobs")
+	defer endSpan(span, &err)
+	return ts.storage.PauseJobs(ctx, namespace, selector, paused)
+}
+
+func (ts *tracedStorage) DeleteJobs(ctx context.Context, namespace string, selector labels.Selector) (jobs []*model.Job, err error) {
+	ctx, span := startSpan(ctx, "DeleteJobs")
+	defer endSpan(span, &err)
+	return ts.storage.DeleteJobs(ctx, namespace, selector)
+}
+
+func (ts *tracedStorage) MarkDueJobsRunning(ctx context.Context) (jobs []*model.Job, err error) {
+	ctx, span := startSpan(ctx, "MarkDueJobsRunning")
+	defer endSpan(span, &err)
+	return ts.storage.MarkDueJobsRunning(ctx)
+}
+
+func (ts *tracedStorage) MarkJobDone(ctx context.Context, job *model.Job) (err error) {
+	ctx, span := startSpan(ctx, "MarkJobDone")
+	defer endSpan(span, &err)
+	return ts.storage.MarkJobDone(ctx, job)
+}
+
+func (ts *tracedStorage) TriggerJob(ctx context.Context, id model.JobId) (job *model.Job, err error) {
+	ctx, span := startSpan(ctx, "TriggerJob")
+	defer endSpan(span, &err)
+	return ts.storage.TriggerJob(ctx, id)
+}
+
+func (ts *tracedStorage) StartRun(ctx context.Context, job *model.Job, schedulerId model.SchedulerId, startTime time.Time) (run *model.Run, err error) {
+	ctx, span := startSpan(ctx, "StartRun")
+	defer endSpan(span, &err)
+	return ts.storage.StartRun(ctx, job, schedulerId, startTime)
+}
+
+func (ts *tracedStorage) FinishRun(ctx context.Context, run *model.Run) (err error) {
+	ctx, span := startSpan(ctx, "FinishRun")
+	defer endSpan(span, &err)
+	return ts.storage.FinishRun(ctx, run)
+}
+
+func (ts *tracedStorage) GetRuns(ctx context.Context, jobId model.JobId, limit uint) (runs []*model.Run, err error) {
+	ctx, span := startSpan(ctx, "GetRuns")
+	defer endSpan(span, &err)
+	return ts.storage.GetRuns(ctx, jobId, limit)
+}
+
+func (ts *tracedStorage) GetRun(ctx context.Context, jobId model.JobId, id model.RunId) (run *model.Run, err error) {
+	ctx, span := startSpan(ctx, "GetRun")
+	defer endSpan(span, &err)
+	return ts.storage.GetRun(ctx, jobId, id)
+}
+
+func (ts *tracedStorage) CancelRun(ctx context.Context, jobId model.JobId, id model.RunId) (run *model.Run, err error) {
+	ctx, span := startSpan(ctx, "CancelRun")
+	defer endSpan(span, &err)
+	return ts.storage.CancelRun(ctx, jobId, id)
+}
+
+func (ts *tracedStorage) GetCancelledRuns(ctx context.Context, ids []model.RunId) (cancelled []model.RunId, err error) {
+	ctx, span := startSpan(ctx, "GetCancelledRuns")
+	defer endSpan(span, &err)
+	return ts.storage.GetCancelledRuns(ctx, ids)
+}
+
+func (ts *tracedStorage) GetJobStats(ctx context.Context, jobId model.JobId, since time.Time) (stats *model.JobStats, err error) {
+	ctx, span := startSpan(ctx, "GetJobStats")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobStats(ctx, jobId, since)
+}
+
+func (ts *tracedStorage) CreateAPIKey(ctx context.Context, name string, role auth.Role, namespaces []string, keyHash []byte) (key *model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "CreateAPIKey")
+	defer endSpan(span, &err)
+	return ts.storage.CreateAPIKey(ctx, name, role, namespaces, keyHash)
+}
+
+func (ts *tracedStorage) AuthenticateAPIKey(ctx context.Context, keyHash []byte) (key *model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "AuthenticateAPIKey")
+	defer endSpan(span, &err)
+	return ts.storage.AuthenticateAPIKey(ctx, keyHash)
+}
+
+func (ts *tracedStorage) RecordAPIKeyUse(ctx context.Context, key *model.APIKey) (err error) {
+	ctx, span := startSpan(ctx, "RecordAPIKeyUse")
+	defer endSpan(span, &err)
+	return ts.storage.RecordAPIKeyUse(ctx, key)
+}
+
+func (ts *tracedStorage) ListAPIKeys(ctx context.Context) (keys []*model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "ListAPIKeys")
+	defer endSpan(span, &err)
+	return ts.storage.ListAPIKeys(ctx)
+}
+
+func (ts *tracedStorage) DeleteAPIKey(ctx context.Context, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteAPIKey")
+	defer endSpan(span, &err)
+	return ts.storage.DeleteAPIKey(ctx, name)
+}
+
+func (ts *tracedStorage) GetAuditEntries(ctx context.Context, filter model.AuditFilter) (entries []*model.AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "GetAuditEntries")
+	defer endSpan(span, &err)
+	return ts.storage.GetAuditEntries(ctx, filter)
+}
+
+func (ts *tracedStorage) GetQuotaUsage(ctx context.Context, namespace string) (usage *model.QuotaUsage, err error) {
+	ctx, span := startSpan(ctx, "GetQuotaUsage")
+	defer endSpan(span, &err)
+	return ts.storage.GetQuotaUsage(ctx, namespace)
+}
+
+func (ts *tracedStorage) SetQuota(ctx context.Context, namespace string, quota model.Quota) (err error) {
+	ctx, span := startSpan(ctx, "SetQuota")
+	defer endSpan(span, &err)
+	return ts.storage.SetQuota(ctx, namespace, quota)
+}
+
+func (ts *tracedStorage) CheckHealth(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "CheckHealth")
+	defer endSpan(span, &err)
+	return ts.storage.CheckHealth(ctx)
+}
+
+func (ts *tracedStorage) RegisterScheduler(ctx context.Context, instance *model.SchedulerInstance) (err error) {
+	ctx, span := startSpan(ctx, "RegisterScheduler")
+	defer endSpan(span, &err)
+	return ts.storage.RegisterScheduler(ctx, instance)
+}
+
+func (ts *tracedStorage) HeartbeatScheduler(ctx context.Context, id model.SchedulerId) (err error) {
+	ctx, span := startSpan(ctx, "HeartbeatScheduler")
+	defer endSpan(span, &err)
+	return ts.storage.HeartbeatScheduler(ctx, id)
+}
+
+func (ts *tracedStorage) StopScheduler(ctx context.Context, id model.SchedulerId) (err error) {
+	ctx, span := startSpan(ctx, "StopScheduler")
+	defer endSpan(span, &err)
+	return ts.storage.StopScheduler(ctx, id)
+}
+
+func (ts *tracedStorage) ListSchedulers(ctx context.Context, limit uint) (instances []*model.SchedulerInstance, err error) {
+	ctx, span := startSpan(ctx, "ListSchedulers")
+	defer endSpan(span, &err)
+	return ts.storage.ListSchedulers(ctx, limit)
+}
+
+func (ts *tracedStorage) CreateNotificationRule(ctx context.Context, rule *model.NotificationRule) (err error) {
+	ctx, span := startSpan(ctx, "CreateNotificationRule")
+	defer endSpan(span, &err)
+	return ts.storage.CreateNotificationRule(ctx, rule)
+}
+
+func (ts *tracedStorage) GetNotificationRule(ctx context.Context, namespace string, id model.NotificationRuleId) (rule *model.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationRule")
+	defer endSpan(span, &err)
+	return ts.storage.GetNotificationRule(ctx, namespace, id)
+}
+
+func (ts *tracedStorage) ListNotificationRules(ctx context.Context, namespace string) (rules []*model.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "ListNotificationRules")
+	defer endSpan(span, &err)
+	return ts.storage.ListNotificationRules(ctx, namespace)
+}
+
+func (ts *tracedStorage) DeleteNotificationRule(ctx context.Context, namespace string, id model.NotificationRuleId) (err error) {
+	ctx, span := startSpan(ctx, "DeleteNotificationRule")
+	defer endSpan(span, &err)
+	return ts.storage.DeleteNotificationRule(ctx, namespace, id)
+}
+
+func (ts *tracedStorage) GetJobNotificationRules(ctx context.Context, job *model.Job) (rules []*model.NotificationRule, err error) {
+	ctx, span := startSpan(ctx, "GetJobNotificationRules")
+	defer endSpan(span, &err)
+	return ts.storage.GetJobNotificationRules(ctx, job)
+}
+
+func (ts *tracedStorage) CreateNotificationDelivery(ctx context.Context, delivery *model.NotificationDelivery) (err error) {
+	ctx, span := startSpan(ctx, "CreateNotificationDelivery")
+	defer endSpan(span, &err)
+	return ts.storage.CreateNotificationDelivery(ctx, delivery)
+}
+
+func (ts *tracedStorage) UpdateNotificationDelivery(ctx context.Context, delivery *model.NotificationDelivery) (err error) {
+	ctx, span := startSpan(ctx, "UpdateNotificationDelivery")
+	defer endSpan(span, &err)
+	return ts.storage.UpdateNotificationDelivery(ctx, delivery)
+}
+
+func (ts *tracedStorage) GetNotificationDeliveries(ctx context.Context, ruleId model.NotificationRuleId, limit uint) (deliveries []*model.NotificationDelivery, err error) {
+	ctx, span := startSpan(ctx, "GetNotificationDeliveries")
+	defer endSpan(span, &err)
+	return ts.storage.GetNotificationDeliveries(ctx, ruleId, limit)
+}
+
+func (ts *tracedStorage) GetSLAViolations(ctx context.Context, namespace string) (violations []*model.SLAViolation, err error) {
+	ctx, span := startSpan(ctx, "GetSLAViolations")
+	defer endSpan(span, &err)
+	return ts.storage.GetSLAViolations(ctx, namespace)
+}
+
+func (ts *tracedStorage) MarkSLAViolations(ctx context.Context) (violations []*model.SLAViolation, err error) {
+	ctx, span := startSpan(ctx, "MarkSLAViolations")
+	defer endSpan(span, &err)
+	return ts.storage.MarkSLAViolations(ctx)
+}
+
+func (ts *tracedStorage) ReclaimJobs(ctx context.Context, grace time.Duration) (reclaimed []*model.ReclaimedJob, err error) {
+	ctx, span := startSpan(ctx, "ReclaimJobs")
+	defer endSpan(span, &err)
+	return ts.storage.ReclaimJobs(ctx, grace)
+}
@@ -0,0 +1,25 @@
+package version
+
+import "runtime/debug"
+
+const revisionLength = 12
+
+// Version of the app. It can be set when building with
+// -ldflags "-X go-work/internal/version.Version=<version>", otherwise it is the
+// VCS revision the app was built from, if known
+var Version = "dev"
+
+func init() {
+	if Version != "dev" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= revisionLength {
+			Version = setting.Value[:revisionLength]
+		}
+	}
+}
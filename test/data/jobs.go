@@ -5,29 +5,38 @@ import (
 )
 
 type JobRequestData struct {
-	Name          string   `json:"name"`
-	CrontabString string   `json:"crontabString"`
-	Command       string   `json:"command"`
-	Arguments     []string `json:"arguments"`
-	Timeout       uint     `json:"timeout"`
+	Name          string            `json:"name"`
+	CrontabString string            `json:"crontabString"`
+	Mode          model.JobMode     `json:"mode,omitempty"`
+	Command       string            `json:"command,omitempty"`
+	Script        string            `json:"script,omitempty"`
+	Interpreter   string            `json:"interpreter,omitempty"`
+	Arguments     []string          `json:"arguments"`
+	Timeout       uint              `json:"timeout"`
+	RunAsUser     string            `json:"runAsUser,omitempty"`
+	RunAsGroup    string            `json:"runAsGroup,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+
+	MaxSuccessInterval uint `json:"maxSuccessInterval,omitempty"`
 }
 
 var InitialJobs = []model.Job{
-	{0,
-		"run_every_minute1",
-		"*/1 * * * *",
-		"python",
-		[]string{"test_job1.py"},
-		15,
+	{
+		Name:          "run_every_minute1",
+		CrontabString: "*/1 * * * *",
+		Mode:          model.CommandMode,
+		Command:       "python",
+		Arguments:     []string{"test_job1.py"},
+		Timeout:       15,
 	},
 	{
-		0,
-		"run_every_2_minutes",
-		"*/2 * * * *",
-		"python",
-		[]string{"test_job2.py"},
-		15,
+		Name:          "run_every_2_minutes",
+		CrontabString: "*/2 * * * *",
+		Mode:          model.CommandMode,
+		Command:       "python",
+		Arguments:     []string{"test_job2.py"},
+		Timeout:       15,
 	},
 }
 
-var JobIntervals = []uint{1, 2}
\ No newline at end of file
+var JobIntervals = []uint{1, 2}
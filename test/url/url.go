@@ -3,6 +3,7 @@ package url
 import (
 	"fmt"
 	"go-work/internal/model"
+	neturl "net/url"
 	"os"
 )
 
@@ -17,6 +18,138 @@ func GetJobByName(name string) string {
 	return fmt.Sprintf("http://localhost:%s/api/v1/job/%s/", os.Getenv("TEST_SERVER_PORT"), name)
 }
 
+func GetRuns(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/runs/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func SLAViolations(namespace string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/sla/violations/", os.Getenv("TEST_SERVER_PORT"), namespace)
+}
+
+func Run(namespace string, jobId model.JobId, id model.RunId) string {
+	return fmt.Sprintf(
+		"http://localhost:%s/api/v1/namespaces/%s/job/%d/runs/%d/",
+		os.Getenv("TEST_SERVER_PORT"),
+		namespace,
+		jobId,
+		id,
+	)
+}
+
+func CancelRun(namespace string, jobId model.JobId, id model.RunId) string {
+	return fmt.Sprintf(
+		"http://localhost:%s/api/v1/namespaces/%s/job/%d/runs/%d/cancel/",
+		os.Getenv("TEST_SERVER_PORT"),
+		namespace,
+		jobId,
+		id,
+	)
+}
+
+func GetJobStats(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/stats/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
 func DeleteJob(id model.JobId) string {
 	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/", os.Getenv("TEST_SERVER_PORT"), id)
 }
+
+func RestoreJob(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/restore/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func TriggerJob(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/trigger/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func UpdateJob(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func GetRevisions(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/revisions/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func DiffRevisions(id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/revisions/diff/", os.Getenv("TEST_SERVER_PORT"), id)
+}
+
+func RollbackJob(id model.JobId, revision uint) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/%d/revisions/%d/rollback/", os.Getenv("TEST_SERVER_PORT"), id, revision)
+}
+
+func APIKeys() string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/key/", os.Getenv("TEST_SERVER_PORT"))
+}
+
+func APIKey(name string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/key/%s/", os.Getenv("TEST_SERVER_PORT"), name)
+}
+
+func AuditEntries(jobId model.JobId, actor string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/audit/?jobId=%d&actor=%s", os.Getenv("TEST_SERVER_PORT"), jobId, actor)
+}
+
+func ListJobs(selector string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/?selector=%s", os.Getenv("TEST_SERVER_PORT"), neturl.QueryEscape(selector))
+}
+
+func PauseJobs(selector string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/pause/?selector=%s", os.Getenv("TEST_SERVER_PORT"), neturl.QueryEscape(selector))
+}
+
+func ResumeJobs(selector string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/resume/?selector=%s", os.Getenv("TEST_SERVER_PORT"), neturl.QueryEscape(selector))
+}
+
+func DeleteJobs(selector string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/job/?selector=%s", os.Getenv("TEST_SERVER_PORT"), neturl.QueryEscape(selector))
+}
+
+func CreateNamespacedJob(namespace string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/job/", os.Getenv("TEST_SERVER_PORT"), namespace)
+}
+
+func GetNamespacedJobById(namespace string, id model.JobId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/job/%d/", os.Getenv("TEST_SERVER_PORT"), namespace, id)
+}
+
+func GetNamespacedJobByName(namespace string, name string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/job/%s/", os.Getenv("TEST_SERVER_PORT"), namespace, name)
+}
+
+func Quota(namespace string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/quota/", os.Getenv("TEST_SERVER_PORT"), namespace)
+}
+
+func Healthz() string {
+	return fmt.Sprintf("http://localhost:%s/healthz", os.Getenv("TEST_SERVER_PORT"))
+}
+
+func Readyz() string {
+	return fmt.Sprintf("http://localhost:%s/readyz", os.Getenv("TEST_SERVER_PORT"))
+}
+
+func Status() string {
+	return fmt.Sprintf("http://localhost:%s/debug/status", os.Getenv("TEST_SERVER_PORT"))
+}
+
+func ListSchedulers() string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/schedulers/", os.Getenv("TEST_SERVER_PORT"))
+}
+
+func NotificationRules(namespace string) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/notification/", os.Getenv("TEST_SERVER_PORT"), namespace)
+}
+
+func NotificationRule(namespace string, id model.NotificationRuleId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/notification/%d/", os.Getenv("TEST_SERVER_PORT"), namespace, id)
+}
+
+func NotificationDeliveries(namespace string, id model.NotificationRuleId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/notification/%d/deliveries/", os.Getenv("TEST_SERVER_PORT"), namespace, id)
+}
+
+func TestNotificationRule(namespace string, id model.NotificationRuleId) string {
+	return fmt.Sprintf("http://localhost:%s/api/v1/namespaces/%s/notification/%d/test/", os.Getenv("TEST_SERVER_PORT"), namespace, id)
+}